@@ -0,0 +1,44 @@
+package ocgcore
+
+import (
+	"errors"
+	"ocgcore/lib"
+)
+
+// Backend runs the duels. The default one is the core, through cgo; tests can
+// use a fake one that doesn't need the core, its scripts or a card database.
+type Backend interface {
+	// Version loads the core if needed and returns its API version.
+	Version() (ProtocolVersion, error)
+	CreateDuel(options lib.DuelOptions) (lib.Duel, error)
+	DestroyDuel(duel lib.Duel)
+	NewCard(duel lib.Duel, info lib.NewCardInfo)
+	// LoadScript returns false when the script failed.
+	LoadScript(duel lib.Duel, buffer []byte, name string) bool
+	StartDuel(duel lib.Duel)
+	Process(duel lib.Duel) lib.ProcessorFlag
+	// GetMessage returns the messages produced since the last call, each one
+	// prefixed by its length as a little endian uint32.
+	GetMessage(duel lib.Duel) []byte
+	SetResponse(duel lib.Duel, response []byte)
+	Query(duel lib.Duel, info lib.QueryInfo) []byte
+	QueryLocation(duel lib.Duel, info lib.QueryInfo) []byte
+	QueryField(duel lib.Duel) []byte
+}
+
+var errNoBackend = errors.New("ocgcore: built without cgo, a Backend is required")
+
+// DefaultBackend returns the core backend, nil when built without cgo.
+func DefaultBackend() Backend {
+	return defaultBackend
+}
+
+func backendOrDefault(b Backend) (Backend, error) {
+	if b != nil {
+		return b, nil
+	}
+	if defaultBackend == nil {
+		return nil, errNoBackend
+	}
+	return defaultBackend, nil
+}
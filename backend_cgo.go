@@ -0,0 +1,80 @@
+//go:build cgo
+// +build cgo
+
+package ocgcore
+
+import (
+	"ocgcore/lib"
+	"sync"
+)
+
+var defaultBackend Backend = coreBackend{}
+
+type coreBackend struct{}
+
+var (
+	versionOnce sync.Once
+	version     ProtocolVersion
+	versionErr  error
+)
+
+// Version loads the core when it's a shared library, see lib.Load. The core
+// is only checked on the first call, a load error is returned by every later
+// call too, so a library path must be loaded before the first duel.
+func (coreBackend) Version() (ProtocolVersion, error) {
+	versionOnce.Do(func() {
+		if versionErr = lib.Load(""); versionErr != nil {
+			return
+		}
+		major, minor := lib.Version()
+		version = ProtocolVersion{Major: major, Minor: minor}
+	})
+	return version, versionErr
+}
+
+func (b coreBackend) CreateDuel(options lib.DuelOptions) (lib.Duel, error) {
+	if _, err := b.Version(); err != nil {
+		return 0, err
+	}
+	return lib.CreateDuel(options)
+}
+
+func (coreBackend) DestroyDuel(duel lib.Duel) {
+	lib.DestroyDuel(duel)
+}
+
+func (coreBackend) NewCard(duel lib.Duel, info lib.NewCardInfo) {
+	lib.DuelNewCard(duel, info)
+}
+
+func (coreBackend) LoadScript(duel lib.Duel, buffer []byte, name string) bool {
+	return lib.LoadScript(duel, buffer, name) != 0
+}
+
+func (coreBackend) StartDuel(duel lib.Duel) {
+	lib.StartDuel(duel)
+}
+
+func (coreBackend) Process(duel lib.Duel) lib.ProcessorFlag {
+	return lib.DuelProcess(duel)
+}
+
+func (coreBackend) GetMessage(duel lib.Duel) []byte {
+	return lib.DuelGetMessage(duel)
+}
+
+func (coreBackend) SetResponse(duel lib.Duel, response []byte) {
+	lib.DuelSetResponse(duel, response)
+}
+
+func (coreBackend) Query(duel lib.Duel, info lib.QueryInfo) []byte {
+	return lib.DuelQuery(duel, info)
+}
+
+func (coreBackend) QueryLocation(duel lib.Duel, info lib.QueryInfo) []byte {
+	return lib.DuelQueryLocation(duel, info)
+}
+
+func (coreBackend) QueryField(duel lib.Duel) []byte {
+	return lib.DuelQueryField(duel)
+}
@@ -0,0 +1,6 @@
+//go:build !cgo
+// +build !cgo
+
+package ocgcore
+
+var defaultBackend Backend
@@ -0,0 +1,331 @@
+// Command bench runs self-play duels in parallel and reports the throughput of
+// the bindings. Both players only do what the core forces them to, so every
+// duel ends by decking out and the work is the same from one run to the next:
+//
+//	go run ./cmd/bench -duels 200 -parallel 16
+//
+// With -corpus, a few messages of each type and query results are added to
+// the seed corpus of the fuzz tests. It must run from the module root.
+//
+// Decoding alone is measured by the benchmarks of the tests:
+//
+//	go test -run - -bench . -benchmem . ./utils
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"ocgcore"
+	"ocgcore/database"
+	"ocgcore/lib"
+	"ocgcore/script"
+	"ocgcore/utils"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const deckSize = 40
+
+// passiveResponse answers a prompt doing as little as possible.
+func passiveResponse(m ocgcore.Message) (ocgcore.Response, error) {
+	switch m := m.(type) {
+	case ocgcore.MessageSelectIdleCMD:
+		if m.ToEP {
+			return ocgcore.ResponseSelectIdleCMD{Action: ocgcore.IdleActionToEP}, nil
+		}
+		return ocgcore.ResponseSelectIdleCMD{Action: ocgcore.IdleActionToBP}, nil
+	case ocgcore.MessageSelectBattleCMD:
+		if m.ToEP {
+			return ocgcore.ResponseSelectBattleCMD{Action: ocgcore.BattleActionToEP}, nil
+		}
+		return ocgcore.ResponseSelectBattleCMD{Action: ocgcore.BattleActionToM2}, nil
+	case ocgcore.MessageSelectChain:
+		if m.Forced && len(m.Chains) > 0 {
+			return ocgcore.ResponseSelectChain{Chain: 0}, nil
+		}
+		return ocgcore.ResponseSelectChain{Chain: -1}, nil
+	case ocgcore.MessageSelectEffectYN:
+		return ocgcore.ResponseSelectEffectYN{Yes: false}, nil
+	case ocgcore.MessageSelectYesNo:
+		return ocgcore.ResponseSelectYesNo{Yes: false}, nil
+	case ocgcore.MessageSelectOption:
+		return ocgcore.ResponseSelectOption{Option: 0}, nil
+	case ocgcore.MessageSelectCard:
+		selected := make([]int, m.Min)
+		for i := range selected {
+			selected[i] = i
+		}
+		return ocgcore.ResponseSelectCard{Select: selected}, nil
+	case ocgcore.MessageSelectUnselectCard:
+		if m.Finishable || len(m.Selects) == 0 {
+			return ocgcore.ResponseSelectUnselectCard{Cancel: true}, nil
+		}
+		return ocgcore.ResponseSelectUnselectCard{Selection: 0}, nil
+	case ocgcore.MessageSelectPlace:
+		if len(m.Places) < m.Count {
+			return nil, errors.New("not enough places")
+		}
+		return ocgcore.ResponseSelectPlace{Places: m.Places[:m.Count]}, nil
+	case ocgcore.MessageSelectPosition:
+		if len(m.Positions) == 0 {
+			return nil, errors.New("no position")
+		}
+		return ocgcore.ResponseSelectPosition{Position: m.Positions[0]}, nil
+	}
+	return nil, fmt.Errorf("unsupported prompt %T", m)
+}
+
+// normalMonsters are the only cards in the decks, they have no effects that
+// could ask for unsupported prompts.
+func normalMonsters(db database.CardDatabase) []uint32 {
+	var codes []uint32
+	for code, card := range db {
+		t := card.Raw.Type
+		if t&lib.CardTypeNormal != 0 && t&(lib.CardTypeToken|lib.CardTypePendulum) == 0 && card.Raw.Alias == 0 {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// selfPlay plays a duel, querying the field on every prompt when query is
+// set.
+func selfPlay(r *rand.Rand, cards []uint32, options ocgcore.CreateDuelOptions, query bool) (int, error) {
+	options.Seed = r.Uint32()
+	duel, err := ocgcore.CreateDuel(options)
+	if err != nil {
+		return 0, err
+	}
+	defer duel.Destroy()
+
+	for player := 0; player < 2; player++ {
+		deck := make([]uint32, deckSize)
+		for i := range deck {
+			deck[i] = cards[r.Intn(len(cards))]
+		}
+		duel.SetupDeck(player, deck, nil, false)
+	}
+
+	// the messages are read until the duel ends, so that it isn't destroyed
+	// while processing
+	messages := 0
+	won := false
+	var prompt ocgcore.Message
+	for m := range duel.Start() {
+		messages++
+		switch m.(type) {
+		case ocgcore.MessageWaitingResponse:
+			if query {
+				if _, err := duel.FieldStatus(); err != nil {
+					return messages, err
+				}
+			}
+			resp, err := passiveResponse(prompt)
+			if err != nil {
+				return messages, err
+			}
+			duel.SendResponse(resp)
+		case ocgcore.MessageWin:
+			won = true
+		default:
+			if _, ok := ocgcore.ResponsePlayer(m); ok {
+				prompt = m
+			}
+		}
+	}
+	if !won {
+		return messages, errors.New("duel ended without a winner")
+	}
+	return messages, nil
+}
+
+// recorder keeps a copy of the messages and the query results produced by
+// the duels.
+type recorder struct {
+	ocgcore.Backend
+
+	lock     sync.Mutex
+	messages [][]byte
+	queries  map[string][][]byte
+}
+
+func (r *recorder) record(target string, data []byte) []byte {
+	r.lock.Lock()
+	if r.queries == nil {
+		r.queries = map[string][][]byte{}
+	}
+	r.queries[target] = append(r.queries[target], append([]byte{}, data...))
+	r.lock.Unlock()
+	return data
+}
+
+func (r *recorder) Query(duel lib.Duel, info lib.QueryInfo) []byte {
+	return r.record("query", r.Backend.Query(duel, info))
+}
+
+func (r *recorder) QueryLocation(duel lib.Duel, info lib.QueryInfo) []byte {
+	return r.record("query_location", r.Backend.QueryLocation(duel, info))
+}
+
+func (r *recorder) QueryField(duel lib.Duel) []byte {
+	return r.record("query_field", r.Backend.QueryField(duel))
+}
+
+// corpusDirs are the seed corpus of the fuzz tests of each recorded input.
+var corpusDirs = map[string]string{
+	"message":        "testdata/fuzz/FuzzReadMessage",
+	"query":          "lib/testdata/fuzz/FuzzParseQuery",
+	"query_location": "lib/testdata/fuzz/FuzzParseQueryLocation",
+	"query_field":    "lib/testdata/fuzz/FuzzParseQueryField",
+}
+
+// corpusPerKind is the number of inputs saved for each message type and
+// each kind of query.
+const corpusPerKind = 3
+
+// saveCorpus adds the first recorded inputs of each kind to the seed corpus,
+// in the format of the go fuzzing engine.
+func (r *recorder) saveCorpus() (int, error) {
+	inputs := map[string][][]byte{"message": r.messages}
+	for target, data := range r.queries {
+		inputs[target] = data
+	}
+
+	saved := 0
+	for target, data := range inputs {
+		dir := corpusDirs[target]
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return saved, err
+		}
+		kinds := map[byte]int{}
+		for _, d := range data {
+			var kind byte
+			if target == "message" && len(d) > 0 {
+				kind = d[0]
+			}
+			if kinds[kind] >= corpusPerKind {
+				continue
+			}
+			kinds[kind]++
+
+			sum := sha256.Sum256(d)
+			path := filepath.Join(dir, hex.EncodeToString(sum[:8]))
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+			if err := ioutil.WriteFile(path, []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", d)), 0644); err != nil {
+				return saved, err
+			}
+			saved++
+		}
+	}
+	return saved, nil
+}
+
+func (r *recorder) GetMessage(duel lib.Duel) []byte {
+	data := r.Backend.GetMessage(duel)
+
+	b := utils.NewReader(append([]byte{}, data...))
+	r.lock.Lock()
+	for b.Len() > 0 {
+		m := b.Bytes(int(b.Uint32()))
+		if b.Err() != nil {
+			break
+		}
+		r.messages = append(r.messages, m)
+	}
+	r.lock.Unlock()
+	return data
+}
+
+func main() {
+	duels := flag.Int("duels", 100, "number of duels")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "duels running at the same time")
+	corpus := flag.Bool("corpus", false, "add the messages and queries to the seed corpus of the fuzz tests")
+	flag.Parse()
+
+	db, err := database.Merge(database.SQLite("cards.cdb"), database.SQLite("release.cdb"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	cards := normalMonsters(db)
+	if len(cards) == 0 {
+		log.Fatal("no normal monsters in the database")
+	}
+	options := ocgcore.CreateDuelOptions{
+		Mode:         ocgcore.DuelModeMR5,
+		CardReader:   db.Reader(),
+		ScriptReader: script.Cache(script.Dir("script"), 64<<20).Read,
+		LogHandler:   func(entry ocgcore.LogEntry) {},
+	}
+	var rec *recorder
+	if *corpus {
+		rec = &recorder{Backend: ocgcore.DefaultBackend()}
+		options.Backend = rec
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var messages, failed int64
+	for w := 0; w < *parallel; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for range next {
+				n, err := selfPlay(r, cards, options, *corpus)
+				atomic.AddInt64(&messages, int64(n))
+				if err != nil {
+					atomic.AddInt64(&failed, 1)
+					log.Printf("duel failed: %v", err)
+				}
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+	for i := 0; i < *duels; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	mallocs := after.Mallocs - before.Mallocs
+	bytes := after.TotalAlloc - before.TotalAlloc
+
+	fmt.Printf("duels:          %d (%d failed), %d in parallel\n", *duels, failed, *parallel)
+	fmt.Printf("elapsed:        %v\n", elapsed)
+	fmt.Printf("duels/minute:   %.1f\n", float64(*duels)/elapsed.Minutes())
+	fmt.Printf("messages:       %d\n", messages)
+	fmt.Printf("messages/s:     %.0f\n", float64(messages)/elapsed.Seconds())
+	if messages > 0 {
+		fmt.Printf("allocs/message: %.1f\n", float64(mallocs)/float64(messages))
+		fmt.Printf("bytes/message:  %.0f\n", float64(bytes)/float64(messages))
+	}
+	if *duels > 0 {
+		fmt.Printf("allocs/duel:    %d\n", mallocs/uint64(*duels))
+	}
+
+	if *corpus {
+		saved, err := rec.saveCorpus()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("corpus:         %d new inputs\n", saved)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"ocgcore"
+	"ocgcore/database"
+	"ocgcore/script"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type puzzleInfo struct {
+	name  string
+	title string
+}
+
+func listPuzzles(dir string) ([]puzzleInfo, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	puzzles := make([]puzzleInfo, len(files))
+	for i, file := range files {
+		puzzles[i].name = strings.TrimSuffix(filepath.Base(file), ".lua")
+		puzzles[i].title = puzzleTitle(file)
+	}
+	return puzzles, nil
+}
+
+// puzzleTitle returns the first comment line of a puzzle, which by
+// convention holds its name.
+func puzzleTitle(fileName string) string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "--") {
+			return strings.TrimSpace(strings.Trim(line, "-[]"))
+		}
+		break
+	}
+	return ""
+}
+
+func printJson(v interface{}) {
+	s, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(s))
+}
+
+func runPuzzle(db database.CardDatabase, dir string, name string) error {
+	fileName := filepath.Join(dir, name+".lua")
+	contents, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	duel, field, err := ocgcore.LoadPuzzle(ocgcore.CreateDuelOptions{
+		Mode:         ocgcore.DuelModeMR5,
+		CardReader:   db.Reader(),
+		ScriptReader: script.Dir("script"),
+	}, filepath.Base(fileName), contents)
+	if err != nil {
+		return err
+	}
+	defer duel.Destroy()
+	printJson(field)
+
+	// responses are read from stdin, one JSON response per line
+	input := bufio.NewScanner(os.Stdin)
+	messages := duel.Start()
+	for m1 := range messages {
+		out, err := ocgcore.MessageToJSON(m1)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+
+		if _, ok := m1.(ocgcore.MessageWaitingResponse); ok {
+			if !input.Scan() {
+				return input.Err()
+			}
+			resp, err := ocgcore.JSONToResponse(input.Bytes())
+			if err != nil {
+				return err
+			}
+			duel.SendResponse(resp)
+		}
+	}
+	return nil
+}
+
+func main() {
+	dir := flag.String("dir", "puzzles", "directory containing the puzzle scripts")
+	list := flag.Bool("list", false, "list the available puzzles")
+	flag.Parse()
+
+	if *list || flag.NArg() == 0 {
+		puzzles, err := listPuzzles(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, p := range puzzles {
+			fmt.Printf("%-30s %s\n", p.name, p.title)
+		}
+		return
+	}
+
+	db, err := database.Merge(database.SQLite("cards.cdb"), database.SQLite("release.cdb"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := runPuzzle(db, *dir, flag.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,46 +1,118 @@
 package main
 
 import (
-	"io/ioutil"
+	"flag"
+	"fmt"
 	"log"
 	"ocgcore/database"
+	"ocgcore/lib"
+	"ocgcore/script"
 	"ocgcore/server"
+	"os"
 	"path/filepath"
-	"regexp"
+	"strings"
+	"time"
 )
 
-func scriptReader() func(path string) []byte {
-	cardScriptRegex := regexp.MustCompile(`c\d+\.lua`)
+const scriptCacheSize = 64 << 20
 
-	return func(path string) []byte {
-		if cardScriptRegex.MatchString(path) {
-			path = filepath.Join("official", path)
+// dirSource loads the custom cards and translations found when loading, so
+// that a reload also picks up new files.
+type dirSource struct{}
+
+func (dirSource) LoadCards(db database.CardDatabase) error {
+	var sources []database.CardSource
+	// custom cards, as .cdb or JSON lines
+	custom, _ := filepath.Glob(filepath.Join("custom", "*.cdb"))
+	for _, fileName := range custom {
+		sources = append(sources, database.SQLite(fileName))
+	}
+	custom, _ = filepath.Glob(filepath.Join("custom", "*.jsonl"))
+	for _, fileName := range custom {
+		sources = append(sources, database.JSONLines(fileName))
+	}
+	// translated texts, named after their locale, like locales/ja.cdb
+	locales, _ := filepath.Glob(filepath.Join("locales", "*.cdb"))
+	for _, fileName := range locales {
+		locale := strings.TrimSuffix(filepath.Base(fileName), ".cdb")
+		sources = append(sources, database.Locale(fileName, locale))
+	}
+
+	for _, source := range sources {
+		if err := source.LoadCards(db); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		contents, err := ioutil.ReadFile(filepath.Join("script", path))
+// cardFilesState returns the size and modification time of the card files,
+// it changes when a file is added, removed or written.
+func cardFilesState() string {
+	var state strings.Builder
+	files := []string{"cards.cdb", "release.cdb"}
+	for _, pattern := range []string{"custom/*.cdb", "custom/*.jsonl", "locales/*.cdb"} {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+	for _, fileName := range files {
+		if info, err := os.Stat(fileName); err == nil {
+			fmt.Fprintf(&state, "%s %d %d\n", fileName, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return state.String()
+}
+
+func watchCardFiles(s *server.Server, interval time.Duration) {
+	last := cardFilesState()
+	for range time.Tick(interval) {
+		state := cardFilesState()
+		if state == last {
+			continue
+		}
+		last = state
+
+		diff, err := s.Reload()
 		if err != nil {
-			log.Println("script reader error: ", err)
-			return nil
+			log.Println("reload error: ", err)
+			continue
 		}
-		return contents
+		log.Printf("added: %v, removed: %v, modified: %v", diff.Added, diff.Removed, diff.Modified)
 	}
 }
 
 func main() {
-	db := database.NewDatabase()
-	if err := db.Load("cards.cdb"); err != nil {
+	watch := flag.Duration("watch", 0, "interval to check the card databases for changes, 0 to disable")
+	adminToken := flag.String("admin-token", "", "token allowed to reload the card databases")
+	core := flag.String("core", "", "core shared library, for builds with the ocgcore_dynamic tag")
+	flag.Parse()
+
+	if err := lib.Load(*core); err != nil {
 		log.Fatal(err)
 	}
-	if err := db.Load("release.cdb"); err != nil {
+
+	strs := database.NewStrings()
+	if err := strs.Load("strings.conf"); err != nil {
 		log.Fatal(err)
 	}
 
+	scripts := script.Cache(script.Overlay(script.Dir(filepath.Join("custom", "script")), script.Dir("script")), scriptCacheSize)
 	s := server.NewServer(server.Config{
 		Address:      "0.0.0.0:8080",
-		ScriptReader: scriptReader(),
-		Database:     db,
+		ScriptReader: scripts.Read,
+		PurgeScripts: scripts.Purge,
+		Sources: []database.CardSource{
+			database.SQLite("cards.cdb"),
+			database.SQLite("release.cdb"),
+			dirSource{},
+		},
+		Strings:    strs,
+		AdminToken: *adminToken,
 	})
 
+	if *watch > 0 {
+		go watchCardFiles(s, *watch)
+	}
 	if err := s.Run(); err != nil {
 		log.Fatal(err)
 	}
@@ -3,31 +3,12 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"ocgcore"
-	"path/filepath"
-	"regexp"
+	"ocgcore/script"
 	"time"
 )
 
-func scriptReader() func(path string) []byte {
-	cardScriptRegex := regexp.MustCompile(`c\d+\.lua`)
-
-	return func(path string) []byte {
-		if cardScriptRegex.MatchString(path) {
-			path = filepath.Join("official", path)
-		}
-
-		contents, err := ioutil.ReadFile(filepath.Join("script", path))
-		if err != nil {
-			fmt.Println(err)
-			return nil
-		}
-		return contents
-	}
-}
-
 func cardReader() func(code uint32) ocgcore.RawCardData {
 	db := newCardDatabase()
 
@@ -50,12 +31,15 @@ func printJson(v interface{}) {
 func test() error {
 	rand.Seed(time.Now().Unix())
 
-	duel := ocgcore.CreateDuel(ocgcore.CreateDuelOptions{
+	duel, err := ocgcore.CreateDuel(ocgcore.CreateDuelOptions{
 		Seed:         rand.Uint32(),
 		Mode:         ocgcore.DuelModeMR5,
 		CardReader:   cardReader(),
-		ScriptReader: scriptReader(),
+		ScriptReader: script.Dir("script"),
 	})
+	if err != nil {
+		return err
+	}
 
 	duel.SetupDeck(0, mainDeck, extraDeck, false)
 	duel.SetupDeck(1, mainDeck, extraDeck, false)
@@ -206,35 +206,37 @@ func parseCoreLocation(l lib.Location) Location {
 }
 
 func ParseCardType(ot lib.CardType) CardType {
+	// tokens also have the monster bit
 	switch {
+	case ot&lib.CardTypeToken != 0:
+		return CardTypeToken
 	case ot&lib.CardTypeMonster != 0:
 		return CardTypeMonster
 	case ot&lib.CardTypeSpell != 0:
 		return CardTypeSpell
 	case ot&lib.CardTypeTrap != 0:
 		return CardTypeTrap
-	case ot&lib.CardTypeToken != 0:
-		return CardTypeToken
 	}
 	return 0
 }
 
 func ParseCardTypeMonster(ot lib.CardType) (mf CardMonsterFrame, mt CardMonsterType, ma CardMonsterAbility, mtu bool, mp bool) {
+	// extra deck and ritual monsters can have the effect bit too
 	switch {
-	case ot&lib.CardTypeNormal != 0:
-		mf = CardMonsterFrameNormal
-	case ot&lib.CardTypeEffect != 0:
-		mf = CardMonsterFrameEffect
+	case ot&lib.CardTypeLink != 0:
+		mf = CardMonsterFrameLink
+	case ot&lib.CardTypeXyz != 0:
+		mf = CardMonsterFrameXyz
+	case ot&lib.CardTypeSynchro != 0:
+		mf = CardMonsterFrameSynchro
 	case ot&lib.CardTypeFusion != 0:
 		mf = CardMonsterFrameFusion
 	case ot&lib.CardTypeRitual != 0:
 		mf = CardMonsterFrameRitual
-	case ot&lib.CardTypeSynchro != 0:
-		mf = CardMonsterFrameSynchro
-	case ot&lib.CardTypeXyz != 0:
-		mf = CardMonsterFrameXyz
-	case ot&lib.CardTypeLink != 0:
-		mf = CardMonsterFrameLink
+	case ot&lib.CardTypeEffect != 0:
+		mf = CardMonsterFrameEffect
+	default:
+		mf = CardMonsterFrameNormal
 	}
 	switch {
 	case ot&lib.CardTypeSpirit != 0:
@@ -257,6 +259,18 @@ func ParseCardTypeMonster(ot lib.CardType) (mf CardMonsterFrame, mt CardMonsterT
 	return
 }
 
+// ParseCardRace returns the type of a monster, the race bits follow the
+// order of CardMonsterType.
+func ParseCardRace(r lib.Race) CardMonsterType {
+	return CardMonsterType(bits.TrailingZeros32(uint32(r)))
+}
+
+// ParseCardAttribute returns the attribute of a monster, the attribute bits
+// follow the order of CardMonsterAttribute.
+func ParseCardAttribute(a lib.Attribute) CardMonsterAttribute {
+	return CardMonsterAttribute(bits.TrailingZeros32(uint32(a)))
+}
+
 func ParseCardTypeSpell(ot lib.CardType) CardSpellType {
 	switch {
 	case ot&lib.CardTypeQuickPlay != 0:
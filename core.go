@@ -1,22 +1,39 @@
 package ocgcore
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
-	"io"
 	"ocgcore/lib"
+	"ocgcore/utils"
+	"sync/atomic"
 )
 
 type CardReader func(code uint32) RawCardData
 type ScriptReader func(path string) []byte
 
 type CreateDuelOptions struct {
-	Seed uint32
-	Mode DuelMode
+	Seed     uint32
+	Mode     DuelMode
+	TestMode bool
+
+	// Format overrides the rules implied by Mode.
+	Format *Format
+
+	// TeamSize is the number of duelists in each team, 1 when zero. The core
+	// learns the team composition from the duelist of each deck, see
+	// OcgDuel.SetupDuelistDeck.
+	TeamSize [2]int
+	// Relay makes the next duelist of a team enter when the previous one
+	// loses, instead of swapping duelists every turn.
+	Relay bool
 
 	CardReader   CardReader
 	ScriptReader ScriptReader
+	// Backend runs the duel, DefaultBackend when nil.
+	Backend Backend
+	// LogHandler receives the messages logged by the core, DefaultLogHandler
+	// when nil.
+	LogHandler LogHandler
 }
 
 type RawCardData lib.CardData
@@ -34,139 +51,178 @@ const (
 	DuelModeMR5
 )
 
-func CreateDuel(options CreateDuelOptions) *OcgDuel {
-	var flags lib.DuelMode
-
-	switch options.Mode {
-	case DuelModeSpeed:
-		flags |= lib.DuelModeSpeed
-	case DuelModeRush:
-		flags |= lib.DuelModeRush
-	case DuelModeMR1:
-		flags |= lib.DuelModeMR1
-	case DuelModeGoat:
-		flags |= lib.DuelModeGoat
-	case DuelModeMR2:
-		flags |= lib.DuelModeMR2
-	case DuelModeMR3:
-		flags |= lib.DuelModeMR3
-	case DuelModeMR4:
-		flags |= lib.DuelModeMR4
-	case DuelModeMR5:
-		flags |= lib.DuelModeMR5
+var lastDuelID uint64
+
+// coreScripts are loaded in every duel, before any card script.
+var coreScripts = []string{"constant.lua", "utility.lua"}
+
+// CreateDuel fails when one of the core scripts is missing or doesn't load. When
+// the core is a shared library, it's loaded on the first call unless
+// lib.Load was called before.
+func CreateDuel(options CreateDuelOptions) (*OcgDuel, error) {
+	backend, err := backendOrDefault(options.Backend)
+	if err != nil {
+		return nil, err
+	}
+
+	format := FormatForMode(options.Mode)
+	if options.Format != nil {
+		format = *options.Format
+	}
+
+	flags := format.Flags
+	if options.TestMode {
+		flags |= lib.DuelTestMode
+	}
+	if options.Relay {
+		flags |= lib.DuelRelay
 	}
 
+	player := lib.Player{
+		StartingLP:        uint32(format.StartingLP),
+		StartingDrawCount: uint32(format.HandSize),
+		DrawCountPerTurn:  uint32(format.DrawCount),
+	}
+	diag := &diagnostics{
+		duelID:  atomic.AddUint64(&lastDuelID, 1),
+		handler: options.LogHandler,
+	}
+	if diag.handler == nil {
+		diag.handler = DefaultLogHandler
+	}
 	duelOptions := lib.DuelOptions{
 		Seed:  0,
 		Flags: flags,
-		Team1: lib.Player{
-			StartingLP:        8000,
-			StartingDrawCount: 5,
-			DrawCountPerTurn:  1,
-		},
-		Team2: lib.Player{
-			StartingLP:        8000,
-			StartingDrawCount: 5,
-			DrawCountPerTurn:  1,
-		},
+		Team1: player,
+		Team2: player,
 		CardReader: func(code uint32) (cardData lib.CardData) {
 			return lib.CardData(options.CardReader(code))
 		},
 		ScriptReader: func(duel lib.Duel, name string) bool {
-			contents := options.ScriptReader(name)
-			if len(contents) == 0 {
-				return false
-			}
-			lib.LoadScript(duel, contents, name)
-			return true
+			return diag.loadScript(backend, duel, options.ScriptReader, name).Status == ScriptLoaded
 		},
-		LogHandler: func(message string, typ int) {
-			fmt.Println("log handler: ", message, typ)
+		LogHandler: func(message string, typ lib.LogType) {
+			diag.addLog(parseLogType(typ), message)
 		},
 		CardReaderDone: func(data lib.CardData) {},
 	}
 
-	duel := lib.CreateDuel(duelOptions)
+	duel, err := backend.CreateDuel(duelOptions)
+	if err != nil {
+		return nil, err
+	}
 
-	lib.LoadScript(duel, options.ScriptReader("constant.lua"), "constant.lua")
-	lib.LoadScript(duel, options.ScriptReader("utility.lua"), "utility.lua")
+	for _, name := range coreScripts {
+		load := diag.loadScript(backend, duel, options.ScriptReader, name)
+		if load.Status == ScriptLoaded {
+			continue
+		}
+		backend.DestroyDuel(duel)
+		if load.Error != "" {
+			return nil, fmt.Errorf("core script %s %s: %s", name, load.Status, load.Error)
+		}
+		return nil, fmt.Errorf("core script %s %s", name, load.Status)
+	}
 
-	return newDuel(duel)
+	d := newDuel(backend, duel, duelOptions, format, options.TeamSize)
+	d.id = diag.duelID
+	d.diagnostics = diag
+	return d, nil
 }
 
-func duelGetMessage(duel lib.Duel) [][]byte {
-	data := lib.DuelGetMessage(duel)
-	dataBuffer := bytes.NewBuffer(data)
+// duelGetMessage splits the messages of a duel, they share the memory of the
+// buffer returned by the backend. On error, the messages before it are still
+// returned.
+func duelGetMessage(backend Backend, duel lib.Duel) ([][]byte, error) {
+	b := utils.NewReader(backend.GetMessage(duel))
 
 	var messages [][]byte
-	for {
-		var length uint32
-		err := binary.Read(dataBuffer, binary.LittleEndian, &length)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			panic(err)
+	for b.Len() > 0 {
+		length := int(b.Field("length").Uint32())
+		message := b.Field("message").Bytes(length)
+		if err := b.Err(); err != nil {
+			return messages, err
 		}
-		b := make([]byte, length)
-		read, err := dataBuffer.Read(b)
-		if err != nil || read != int(length) {
-			panic(err)
-		}
-		messages = append(messages, b)
+		messages = append(messages, message)
 	}
-	return messages
+	return messages, nil
+}
+
+// FieldStatus queries the field of a duel run by the default backend.
+func FieldStatus(duel lib.Duel, format Format) (Field, error) {
+	return fieldStatus(defaultBackend, duel, format)
 }
 
-func FieldStatus(duel lib.Duel) (field Field) {
-	loadFieldPlayer(duel, &field.Player1, 0)
-	loadFieldPlayer(duel, &field.Player2, 1)
+func fieldStatus(backend Backend, duel lib.Duel, format Format) (field Field, err error) {
+	if err = loadFieldPlayer(backend, duel, format, &field.Player1, 0); err != nil {
+		return Field{}, err
+	}
+	if err = loadFieldPlayer(backend, duel, format, &field.Player2, 1); err != nil {
+		return Field{}, err
+	}
 	return
 }
 
-func loadFieldPlayer(duel lib.Duel, player *FieldPlayer, con uint8) {
+func loadFieldPlayer(backend Backend, duel lib.Duel, format Format, player *FieldPlayer, con uint8) error {
 	flagsField := lib.QueryCode |
 		lib.QueryLevel | lib.QueryPosition |
 		lib.QueryAttack | lib.QueryDefense | lib.QueryEquipCard |
 		lib.QueryCounters | lib.QueryLScale | lib.QueryRScale
 	flagsDeck := lib.QueryCode | lib.QueryPosition
 
-	player.Deck = parseFieldDeckCards(duelQueryLocation(duel, lib.QueryInfo{Flags: flagsDeck, Controller: con, Location: lib.LocationDeck}))
-	player.ExtraDeck = parseFieldDeckCards(duelQueryLocation(duel, lib.QueryInfo{Flags: flagsDeck, Controller: con, Location: lib.LocationDeck}))
-	player.Grave = parseFieldDeckCards(duelQueryLocation(duel, lib.QueryInfo{Flags: flagsDeck, Controller: con, Location: lib.LocationDeck}))
-	player.Banished = parseFieldDeckCards(duelQueryLocation(duel, lib.QueryInfo{Flags: flagsDeck, Controller: con, Location: lib.LocationDeck}))
-	player.Hand = parseFieldDeckCards(duelQueryLocation(duel, lib.QueryInfo{Flags: flagsDeck, Controller: con, Location: lib.LocationHand}))
-
-	monsters := duelQueryLocation(duel, lib.QueryInfo{Flags: flagsField, Controller: con, Location: lib.LocationMZone})
-	for i := 0; i < 5; i++ {
-		if monsters[i] != nil {
-			m := parseFieldCard(monsters[i])
-			player.Monsters[i] = &m
-		}
+	decks := []struct {
+		cards    *[]FieldDeckCard
+		location lib.Location
+	}{
+		{&player.Deck, lib.LocationDeck},
+		{&player.ExtraDeck, lib.LocationExtra},
+		{&player.Grave, lib.LocationGrave},
+		{&player.Banished, lib.LocationRemoved},
+		{&player.Hand, lib.LocationHand},
 	}
-	for i := 0; i < 2; i++ {
-		if monsters[5+i] != nil {
-			s := parseFieldCard(monsters[5+i])
-			player.PendulumZones[i] = &s
+	for _, deck := range decks {
+		cards, err := duelQueryLocation(backend, duel, lib.QueryInfo{Flags: flagsDeck, Controller: con, Location: deck.location})
+		if err != nil {
+			return err
 		}
+		*deck.cards = parseFieldDeckCards(cards)
 	}
 
-	spells := duelQueryLocation(duel, lib.QueryInfo{Flags: flagsField, Controller: con, Location: lib.LocationSZone})
-	for i := 0; i < 5; i++ {
-		if spells[i] != nil {
-			s := parseFieldCard(spells[i])
-			player.Spells[i] = &s
-		}
+	columns := format.columnSequences()
+
+	monsters, err := duelQueryLocation(backend, duel, lib.QueryInfo{Flags: flagsField, Controller: con, Location: lib.LocationMZone})
+	if err != nil {
+		return err
+	}
+	player.Monsters = parseFieldZones(monsters, columns)
+	if format.ExtraMonsterZones() {
+		player.ExtraMonsters = parseFieldZones(monsters, []int{5, 6})
 	}
-	if spells[5] != nil {
+
+	spells, err := duelQueryLocation(backend, duel, lib.QueryInfo{Flags: flagsField, Controller: con, Location: lib.LocationSZone})
+	if err != nil {
+		return err
+	}
+	player.Spells = parseFieldZones(spells, columns)
+	if len(spells) > 5 && spells[5] != nil {
 		s := parseFieldCard(spells[5])
 		player.FieldSpell = &s
 	}
-	for i := 0; i < 2; i++ {
-		if spells[6+i] != nil {
-			s := parseFieldCard(spells[6+i])
-			player.PendulumZones[i] = &s
+	if format.SeparatePendulumZones() {
+		player.PendulumZones = parseFieldZones(spells, []int{6, 7})
+	}
+	return nil
+}
+
+func parseFieldZones(cards []lib.ParsedQueryResult, sequences []int) []*FieldCard {
+	zones := make([]*FieldCard, len(sequences))
+	for i, seq := range sequences {
+		if seq < len(cards) && cards[seq] != nil {
+			c := parseFieldCard(cards[seq])
+			zones[i] = &c
 		}
 	}
+	return zones
 }
 
 func parseFieldDeckCards(cards []lib.ParsedQueryResult) []FieldDeckCard {
@@ -177,20 +233,28 @@ func parseFieldDeckCards(cards []lib.ParsedQueryResult) []FieldDeckCard {
 	return res
 }
 
+// queryUint32 is zero for the queries missing or too short.
+func queryUint32(data lib.ParsedQueryResult, query lib.Query) uint32 {
+	if v := data[query]; len(v) >= 4 {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
 func parseFieldDeckCard(data lib.ParsedQueryResult) (card FieldDeckCard) {
-	card.Code = binary.LittleEndian.Uint32(data[lib.QueryCode])
-	card.Position = parseCorePosition(lib.Position(binary.LittleEndian.Uint32(data[lib.QueryPosition]))).Face()
+	card.Code = queryUint32(data, lib.QueryCode)
+	card.Position = parseCorePosition(lib.Position(queryUint32(data, lib.QueryPosition))).Face()
 	return
 }
 
 func parseFieldCard(data lib.ParsedQueryResult) (card FieldCard) {
-	card.Code = binary.LittleEndian.Uint32(data[lib.QueryCode])
-	card.Position = parseCorePosition(lib.Position(binary.LittleEndian.Uint32(data[lib.QueryPosition])))
-	card.Level = int(binary.LittleEndian.Uint32(data[lib.QueryLevel]))
-	card.Defense = int(binary.LittleEndian.Uint32(data[lib.QueryDefense]))
-	card.Attack = int(binary.LittleEndian.Uint32(data[lib.QueryAttack]))
-	card.LScale = int(binary.LittleEndian.Uint32(data[lib.QueryLScale]))
-	card.RScale = int(binary.LittleEndian.Uint32(data[lib.QueryRScale]))
+	card.Code = queryUint32(data, lib.QueryCode)
+	card.Position = parseCorePosition(lib.Position(queryUint32(data, lib.QueryPosition)))
+	card.Level = int(queryUint32(data, lib.QueryLevel))
+	card.Defense = int(queryUint32(data, lib.QueryDefense))
+	card.Attack = int(queryUint32(data, lib.QueryAttack))
+	card.LScale = int(queryUint32(data, lib.QueryLScale))
+	card.RScale = int(queryUint32(data, lib.QueryRScale))
 	return
 }
 
@@ -205,11 +269,11 @@ type FieldPlayer struct {
 	Hand          []FieldDeckCard `json:"hand"`
 	Grave         []FieldDeckCard `json:"grave"`
 	Banished      []FieldDeckCard `json:"banished"`
-	Monsters      [5]*FieldCard   `json:"monsters"`
-	Spells        [5]*FieldCard   `json:"spells"`
+	Monsters      []*FieldCard    `json:"monsters"`
+	Spells        []*FieldCard    `json:"spells"`
 	FieldSpell    *FieldCard      `json:"field_spell"`
-	PendulumZones [2]*FieldCard   `json:"pendulum_zones"`
-	ExtraMonsters [2]*FieldCard   `json:"extra_monsters"`
+	PendulumZones []*FieldCard    `json:"pendulum_zones,omitempty"`
+	ExtraMonsters []*FieldCard    `json:"extra_monsters,omitempty"`
 }
 
 type FieldCard struct {
@@ -227,8 +291,8 @@ type FieldDeckCard struct {
 	Position FacePosition `json:"position"`
 }
 
-func duelQueryOverlay(duel lib.Duel, flags lib.Query, con uint8, loc lib.Location, seq uint32, overlaySeq uint32) lib.ParsedQueryResult {
-	return duelQueryInfo(duel, lib.QueryInfo{
+func duelQueryOverlay(backend Backend, duel lib.Duel, flags lib.Query, con uint8, loc lib.Location, seq uint32, overlaySeq uint32) (lib.ParsedQueryResult, error) {
+	return duelQueryInfo(backend, duel, lib.QueryInfo{
 		Flags:           flags,
 		Controller:      con,
 		Location:        loc,
@@ -237,8 +301,8 @@ func duelQueryOverlay(duel lib.Duel, flags lib.Query, con uint8, loc lib.Locatio
 	})
 }
 
-func duelQuery(duel lib.Duel, flags lib.Query, controller uint8, location lib.Location, sequence uint32) lib.ParsedQueryResult {
-	return duelQueryInfo(duel, lib.QueryInfo{
+func duelQuery(backend Backend, duel lib.Duel, flags lib.Query, controller uint8, location lib.Location, sequence uint32) (lib.ParsedQueryResult, error) {
+	return duelQueryInfo(backend, duel, lib.QueryInfo{
 		Flags:      flags,
 		Controller: controller,
 		Location:   location,
@@ -246,14 +310,14 @@ func duelQuery(duel lib.Duel, flags lib.Query, controller uint8, location lib.Lo
 	})
 }
 
-func duelQueryInfo(duel lib.Duel, info lib.QueryInfo) lib.ParsedQueryResult {
-	return lib.ParseQuery(lib.DuelQuery(duel, info))
+func duelQueryInfo(backend Backend, duel lib.Duel, info lib.QueryInfo) (lib.ParsedQueryResult, error) {
+	return lib.ParseQuery(backend.Query(duel, info))
 }
 
-func duelQueryLocation(duel lib.Duel, info lib.QueryInfo) []lib.ParsedQueryResult {
-	return lib.ParseQueryLocation(lib.DuelQueryLocation(duel, info))
+func duelQueryLocation(backend Backend, duel lib.Duel, info lib.QueryInfo) ([]lib.ParsedQueryResult, error) {
+	return lib.ParseQueryLocation(backend.QueryLocation(duel, info))
 }
 
-func duelQueryField(duel lib.Duel) lib.ParsedQueryField {
-	return lib.ParseQueryField(lib.DuelQueryField(duel))
+func duelQueryField(backend Backend, duel lib.Duel) (lib.ParsedQueryField, error) {
+	return lib.ParseQueryField(backend.QueryField(duel))
 }
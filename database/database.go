@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"ocgcore"
 	"ocgcore/lib"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -23,6 +24,10 @@ type CardMonster struct {
 	Tuner     bool                         `json:"tuner"`
 	Attack    int                          `json:"attack"`
 	Defense   int                          `json:"defense"`
+	// only one of Level, Rank and LinkRating is set, depending on the frame
+	Level      int `json:"level,omitempty"`
+	Rank       int `json:"rank,omitempty"`
+	LinkRating int `json:"link_rating,omitempty"`
 
 	Pendulum *CardMonsterPendulum     `json:"pendulum,omitempty"`
 	Link     []ocgcore.CardLinkMarker `json:"link,omitempty"`
@@ -39,6 +44,7 @@ type CardTrap struct {
 type Card struct {
 	Name        string           `json:"name"`
 	Description string           `json:"description"`
+	Archetypes  []string         `json:"archetypes,omitempty"`
 	Type        ocgcore.CardType `json:"type"`
 	Monster     *CardMonster     `json:"monster,omitempty"`
 	Spell       *CardSpell       `json:"spell,omitempty"`
@@ -51,13 +57,19 @@ type CardEntry struct {
 	Raw         ocgcore.RawCardData
 	Name        string
 	Description string
+	Pool        ocgcore.CardPool
 
 	stringIndexes [16]int
 	strings       []string
+	locales       map[string]*LocalizedText
 }
 
+// Str returns one of the 16 card strings, empty when unset.
 func (c *CardEntry) Str(i int) string {
-	return c.strings[c.stringIndexes[i]]
+	if i < 0 || i >= len(c.stringIndexes) || c.stringIndexes[i] == 0 {
+		return ""
+	}
+	return c.strings[c.stringIndexes[i]-1]
 }
 
 type CardDatabase map[uint32]*CardEntry
@@ -66,48 +78,40 @@ func NewDatabase() CardDatabase {
 	return CardDatabase{}
 }
 
+// DeckCard returns the card pool and alias of a card, it can be passed to
+// ocgcore.Format.ValidateDeck.
+func (c CardDatabase) DeckCard(code uint32) (ocgcore.DeckCard, bool) {
+	card, ok := c[code]
+	if !ok {
+		return ocgcore.DeckCard{}, false
+	}
+	return ocgcore.DeckCard{Pool: card.Pool, Alias: card.Raw.Alias}, true
+}
+
 type sqliteDatabaseSelect struct {
 	dataId        uint32
 	dataOt        uint32
 	dataAlias     uint32
 	dataType      uint32
-	dataLevel     uint32
+	dataLevel     int64
 	dataRace      uint32
 	dataAttribute uint32
 	dataCategory  uint32
-	dataSetCode   uint64
+	dataSetCode   int64
 	dataAtk       int32
 	dataDef       int32
-	name          string
-	desc          string
-	str           [16]string
+	name          sql.NullString
+	desc          sql.NullString
+	str           [16]sql.NullString
 }
 
 func (s *sqliteDatabaseSelect) toCard(raw *ocgcore.RawCardData, card *Card) {
-	card.Name = s.name
-	card.Description = s.desc
-
+	card.Name = s.name.String
 	card.Type = ocgcore.ParseCardType(raw.Type)
 
 	switch card.Type {
 	case ocgcore.CardTypeMonster:
-		card.Monster = new(CardMonster)
-
-		var pendulum bool
-		card.Monster.Frame, card.Monster.Type, card.Monster.Ability, card.Monster.Tuner, pendulum =
-			ocgcore.ParseCardTypeMonster(raw.Type)
-
-		if pendulum {
-			card.Monster.Pendulum = &CardMonsterPendulum{
-				Description: s.desc,
-				LScale:      int(raw.LScale),
-				RScale:      int(raw.RScale),
-			}
-		}
-		if card.Monster.Frame == ocgcore.CardMonsterFrameLink {
-			// TODO: parse link markers
-			card.Monster.Link = ocgcore.ParseLinkMarkers(raw.LinkMarker)
-		}
+		card.Monster = s.toMonster(raw)
 	case ocgcore.CardTypeSpell:
 		card.Spell = &CardSpell{
 			Type: ocgcore.ParseCardTypeSpell(raw.Type),
@@ -117,10 +121,86 @@ func (s *sqliteDatabaseSelect) toCard(raw *ocgcore.RawCardData, card *Card) {
 			Type: ocgcore.ParseCardTypeTrap(raw.Type),
 		}
 	case ocgcore.CardTypeToken:
-		card.Token = new(CardMonster)
-		card.Monster.Frame, card.Monster.Type, card.Monster.Ability, card.Monster.Tuner, _ =
-			ocgcore.ParseCardTypeMonster(raw.Type)
+		card.Token = s.toMonster(raw)
+	}
+	setCardText(card, s.desc.String)
+}
+
+func (s *sqliteDatabaseSelect) toMonster(raw *ocgcore.RawCardData) *CardMonster {
+	m := &CardMonster{
+		Attribute: ocgcore.ParseCardAttribute(raw.Attribute),
+		Type:      ocgcore.ParseCardRace(raw.Race),
+		Attack:    int(raw.Attack),
+		Defense:   int(raw.Defense),
+	}
+
+	var pendulum bool
+	m.Frame, _, m.Ability, m.Tuner, pendulum = ocgcore.ParseCardTypeMonster(raw.Type)
+
+	level := int(raw.Level)
+	if s.dataLevel < 0 {
+		level = -level
+	}
+	switch m.Frame {
+	case ocgcore.CardMonsterFrameLink:
+		m.LinkRating = level
+		m.Link = ocgcore.ParseLinkMarkers(raw.LinkMarker)
+	case ocgcore.CardMonsterFrameXyz:
+		m.Rank = level
+	default:
+		m.Level = level
+	}
+
+	if pendulum {
+		m.Pendulum = &CardMonsterPendulum{
+			LScale: int(raw.LScale),
+			RScale: int(raw.RScale),
+		}
+	}
+	return m
+}
+
+// setCardText sets the description of a card, splitting the pendulum effect
+// from the monster text.
+func setCardText(card *Card, desc string) {
+	card.Description = desc
+	if card.Monster == nil || card.Monster.Pendulum == nil {
+		return
+	}
+
+	pendulum, monster, ok := splitPendulumText(desc)
+	if !ok {
+		return
 	}
+	card.Monster.Pendulum.Description = pendulum
+	card.Description = monster
+}
+
+// splitPendulumText splits a pendulum card text on the dashed line between the
+// pendulum effect and the monster text, dropping the section headers such as
+// "[ Pendulum Effect ]".
+func splitPendulumText(desc string) (pendulum string, monster string, ok bool) {
+	lines := strings.Split(strings.ReplaceAll(desc, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) < 3 || strings.Trim(line, "-") != "" {
+			continue
+		}
+		pendulum = joinSection(lines[:i])
+		monster = joinSection(lines[i+1:])
+		return pendulum, monster, true
+	}
+	return "", desc, false
+}
+
+func joinSection(lines []string) string {
+	if len(lines) > 0 {
+		header := strings.TrimSpace(lines[0])
+		if strings.HasPrefix(header, "[") && strings.HasSuffix(header, "]") {
+			lines = lines[1:]
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
 func (s *sqliteDatabaseSelect) toRaw(card *ocgcore.RawCardData) {
@@ -129,7 +209,7 @@ func (s *sqliteDatabaseSelect) toRaw(card *ocgcore.RawCardData) {
 
 	card.SetCodes = nil
 	for i := 0; i < 4; i++ {
-		setCode := uint16((s.dataSetCode >> (i * 16)) & 0xffff)
+		setCode := uint16((uint64(s.dataSetCode) >> (i * 16)) & 0xffff)
 		if setCode != 0 {
 			card.SetCodes = append(card.SetCodes, setCode)
 		}
@@ -144,16 +224,35 @@ func (s *sqliteDatabaseSelect) toRaw(card *ocgcore.RawCardData) {
 		card.Defense = 0
 	}
 
+	// negative levels are stored as is, without scales
 	if s.dataLevel < 0 {
-		card.Level = -(s.dataLevel & 0xff)
+		card.Level = uint32(-s.dataLevel) & 0xff
 	} else {
-		card.Level = s.dataLevel & 0xff
+		card.Level = uint32(s.dataLevel) & 0xff
+		card.LScale = uint32(s.dataLevel>>24) & 0xff
+		card.RScale = uint32(s.dataLevel>>16) & 0xff
 	}
-	card.LScale = (s.dataLevel >> 16) & 0xff
-	card.RScale = (s.dataLevel >> 24) & 0xff
 	card.Race = lib.Race(s.dataRace)
 	card.Attribute = lib.Attribute(s.dataAttribute)
-	return
+}
+
+func (s *sqliteDatabaseSelect) toEntry() *CardEntry {
+	card := &CardEntry{}
+
+	s.toRaw(&card.Raw)
+	s.toCard(&card.Raw, &card.Card)
+
+	card.Name = s.name.String
+	card.Description = s.desc.String
+	card.Pool = ocgcore.CardPool(s.dataOt)
+
+	for i := 0; i < 16; i++ {
+		if s.str[i].String != "" {
+			card.strings = append(card.strings, s.str[i].String)
+			card.stringIndexes[i] = len(card.strings)
+		}
+	}
+	return card
 }
 
 func (c CardDatabase) Load(fileName string) error {
@@ -170,12 +269,14 @@ FROM datas d
 LEFT JOIN texts t ON d.id = t.id`)
 
 	if err != nil {
+		_ = db.Close()
 		return err
 	}
-
-	var c1 sqliteDatabaseSelect
+	defer db.Close()
+	defer datas.Close()
 
 	for datas.Next() {
+		var c1 sqliteDatabaseSelect
 		err = datas.Scan(
 			&c1.dataId, &c1.dataOt, &c1.dataAlias, &c1.dataSetCode, &c1.dataType, &c1.dataAtk, &c1.dataDef, &c1.dataLevel, &c1.dataRace, &c1.dataAttribute, &c1.dataCategory,
 			&c1.name, &c1.desc, &c1.str[0], &c1.str[1], &c1.str[2], &c1.str[3], &c1.str[4], &c1.str[5], &c1.str[6], &c1.str[7], &c1.str[8], &c1.str[9], &c1.str[10], &c1.str[11], &c1.str[12], &c1.str[13], &c1.str[14], &c1.str[15],
@@ -184,25 +285,8 @@ LEFT JOIN texts t ON d.id = t.id`)
 			return err
 		}
 
-		card := &CardEntry{}
-
-		c1.toRaw(&card.Raw)
-		c1.toCard(&card.Raw, &card.Card)
-
-		card.Name = c1.name
-		card.Description = c1.desc
-
-		for i := 0; i < 16; i++ {
-			if c1.str[i] != "" {
-				card.stringIndexes[i] = len(card.strings)
-				card.strings = append(card.strings, c1.str[i])
-			} else {
-				card.stringIndexes[i] = 0
-			}
-		}
-
+		card := c1.toEntry()
 		c[c1.dataId] = card
 	}
-	return nil
-
+	return datas.Err()
 }
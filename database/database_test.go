@@ -0,0 +1,212 @@
+//go:build cgo
+// +build cgo
+
+package database
+
+import (
+	"database/sql"
+	"ocgcore"
+	"ocgcore/lib"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testSchema = `
+CREATE TABLE datas(id integer primary key, ot integer, alias integer, setcode integer, type integer, atk integer, def integer, level integer, race integer, attribute integer, category integer);
+CREATE TABLE texts(id integer primary key, name text, desc text, str1 text, str2 text, str3 text, str4 text, str5 text, str6 text, str7 text, str8 text, str9 text, str10 text, str11 text, str12 text, str13 text, str14 text, str15 text, str16 text);
+`
+
+const pendulumText = "[ Pendulum Effect ]\r\nOnce per turn.\r\n----------------------------------------\r\n[ Monster Effect ]\r\nCannot be destroyed.\r\nBy battle."
+
+// writeTestDatabase creates a .cdb with one row per case of the conversion.
+func writeTestDatabase(t *testing.T) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "test.cdb")
+	db, err := sql.Open("sqlite3", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{testSchema, nil},
+		// token
+		{`INSERT INTO datas VALUES (100, 3, 0, 0, ?, 0, 0, 1, ?, ?, 0)`,
+			[]interface{}{lib.CardTypeMonster | lib.CardTypeToken, lib.RaceFiend, lib.AttributeDark}},
+		{`INSERT INTO texts (id, name, desc) VALUES (100, 'Token', '')`, nil},
+		// pendulum with scales 1 and 8, two archetypes and sparse strings
+		{`INSERT INTO datas VALUES (200, 3, 0, ?, ?, 1800, 1200, ?, ?, ?, 0)`,
+			[]interface{}{0x0001000000a2, lib.CardTypeMonster | lib.CardTypeEffect | lib.CardTypePendulum, 1<<24 | 8<<16 | 4, lib.RaceSpellCaster, lib.AttributeLight}},
+		{`INSERT INTO texts (id, name, desc, str1, str2, str3, str16) VALUES (200, 'Pendulum', ?, '', 'Second', NULL, 'Last')`,
+			[]interface{}{pendulumText}},
+		// negative level
+		{`INSERT INTO datas VALUES (300, 3, 0, 0, ?, 0, 0, -2, ?, ?, 0)`,
+			[]interface{}{lib.CardTypeMonster | lib.CardTypeEffect, lib.RaceMachine, lib.AttributeEarth}},
+		{`INSERT INTO texts (id, name, desc) VALUES (300, 'Negative', 'Level -2.')`, nil},
+		// link 3, the markers are stored in the defense
+		{`INSERT INTO datas VALUES (400, 3, 0, 0, ?, 2300, ?, 3, ?, ?, 0)`,
+			[]interface{}{lib.CardTypeMonster | lib.CardTypeEffect | lib.CardTypeLink, lib.LinkMarkerTop | lib.LinkMarkerBottomLeft | lib.LinkMarkerBottomRight, lib.RaceCyberse, lib.AttributeDark}},
+		{`INSERT INTO texts (id, name, desc) VALUES (400, 'Link', '')`, nil},
+		// texts row with NULL columns, and no texts row at all
+		{`INSERT INTO datas VALUES (500, 3, 0, 0, ?, 0, 0, 0, 0, 0, 0)`,
+			[]interface{}{lib.CardTypeSpell | lib.CardTypeQuickPlay}},
+		{`INSERT INTO texts (id, name) VALUES (500, 'Null')`, nil},
+		{`INSERT INTO datas VALUES (600, 3, 0, 0, ?, 0, 0, 0, 0, 0, 0)`,
+			[]interface{}{lib.CardTypeTrap}},
+	}
+	for _, s := range statements {
+		if _, err := db.Exec(s.query, s.args...); err != nil {
+			t.Fatalf("%s: %v", s.query, err)
+		}
+	}
+	return fileName
+}
+
+func loadTestDatabase(t *testing.T) CardDatabase {
+	t.Helper()
+	db := NewDatabase()
+	if err := db.Load(writeTestDatabase(t)); err != nil {
+		t.Fatal(err)
+	}
+	if len(db) != 6 {
+		t.Fatalf("%d cards loaded, want 6", len(db))
+	}
+	return db
+}
+
+func TestLoadToken(t *testing.T) {
+	card := loadTestDatabase(t)[100]
+
+	if card.Card.Type != ocgcore.CardTypeToken {
+		t.Errorf("type %v, want token", card.Card.Type)
+	}
+	if card.Card.Monster != nil {
+		t.Errorf("token has a monster %+v", card.Card.Monster)
+	}
+	if card.Card.Token == nil || card.Card.Token.Level != 1 {
+		t.Fatalf("token %+v, want level 1", card.Card.Token)
+	}
+	if card.Card.Token.Attribute != ocgcore.ParseCardAttribute(lib.AttributeDark) {
+		t.Errorf("token attribute %v", card.Card.Token.Attribute)
+	}
+}
+
+func TestLoadPendulum(t *testing.T) {
+	card := loadTestDatabase(t)[200]
+
+	if card.Raw.Level != 4 || card.Raw.LScale != 1 || card.Raw.RScale != 8 {
+		t.Errorf("level %d, scales %d/%d, want 4, 1/8", card.Raw.Level, card.Raw.LScale, card.Raw.RScale)
+	}
+	if !reflect.DeepEqual(card.Raw.SetCodes, []uint16{0xa2, 0x1}) {
+		t.Errorf("setcodes %x", card.Raw.SetCodes)
+	}
+	m := card.Card.Monster
+	if m == nil || m.Pendulum == nil {
+		t.Fatalf("monster %+v is not a pendulum", m)
+	}
+	if m.Level != 4 || m.Pendulum.LScale != 1 || m.Pendulum.RScale != 8 {
+		t.Errorf("monster level %d, scales %d/%d", m.Level, m.Pendulum.LScale, m.Pendulum.RScale)
+	}
+	if m.Pendulum.Description != "Once per turn." {
+		t.Errorf("pendulum text %q", m.Pendulum.Description)
+	}
+	if card.Card.Description != "Cannot be destroyed.\nBy battle." {
+		t.Errorf("monster text %q", card.Card.Description)
+	}
+	if card.Description != pendulumText {
+		t.Errorf("entry text %q, want the text as stored", card.Description)
+	}
+}
+
+func TestLoadNegativeLevel(t *testing.T) {
+	card := loadTestDatabase(t)[300]
+
+	if card.Raw.Level != 2 || card.Raw.LScale != 0 || card.Raw.RScale != 0 {
+		t.Errorf("level %d, scales %d/%d, want 2 without scales", card.Raw.Level, card.Raw.LScale, card.Raw.RScale)
+	}
+	if m := card.Card.Monster; m == nil || m.Level != -2 || m.Pendulum != nil {
+		t.Errorf("monster %+v, want level -2", m)
+	}
+}
+
+func TestLoadLink(t *testing.T) {
+	card := loadTestDatabase(t)[400]
+
+	want := lib.LinkMarkerTop | lib.LinkMarkerBottomLeft | lib.LinkMarkerBottomRight
+	if card.Raw.LinkMarker != want || card.Raw.Defense != 0 || card.Raw.Attack != 2300 {
+		t.Errorf("markers %b, attack %d, defense %d", card.Raw.LinkMarker, card.Raw.Attack, card.Raw.Defense)
+	}
+	m := card.Card.Monster
+	if m == nil || m.Frame != ocgcore.CardMonsterFrameLink {
+		t.Fatalf("monster %+v is not a link", m)
+	}
+	if m.LinkRating != 3 || m.Level != 0 || m.Rank != 0 {
+		t.Errorf("link rating %d, level %d, rank %d", m.LinkRating, m.Level, m.Rank)
+	}
+	markers := []ocgcore.CardLinkMarker{ocgcore.CardLinkMarkerBottomLeft, ocgcore.CardLinkMarkerBottomRight, ocgcore.CardLinkMarkerTop}
+	if !reflect.DeepEqual(m.Link, markers) {
+		t.Errorf("markers %v, want %v", m.Link, markers)
+	}
+}
+
+func TestLoadNullTexts(t *testing.T) {
+	db := loadTestDatabase(t)
+
+	for code, name := range map[uint32]string{500: "Null", 600: ""} {
+		card := db[code]
+		if card.Name != name || card.Card.Name != name {
+			t.Errorf("card %d: name %q, want %q", code, card.Name, name)
+		}
+		if card.Description != "" || card.Card.Description != "" {
+			t.Errorf("card %d: description %q", code, card.Description)
+		}
+		for i := 0; i < 16; i++ {
+			if s := card.Str(i); s != "" {
+				t.Errorf("card %d: string %d is %q", code, i, s)
+			}
+		}
+	}
+	if db[500].Card.Spell == nil || db[600].Card.Trap == nil {
+		t.Errorf("spell %+v, trap %+v", db[500].Card, db[600].Card)
+	}
+}
+
+func TestStr(t *testing.T) {
+	card := loadTestDatabase(t)[200]
+
+	want := map[int]string{1: "Second", 15: "Last"}
+	for i := -1; i <= 16; i++ {
+		if s := card.Str(i); s != want[i] {
+			t.Errorf("string %d is %q, want %q", i, s, want[i])
+		}
+	}
+	if len(card.strings) != 2 {
+		t.Errorf("%d strings kept, want 2", len(card.strings))
+	}
+}
+
+func TestSplitPendulumText(t *testing.T) {
+	tests := []struct {
+		desc     string
+		pendulum string
+		monster  string
+		ok       bool
+	}{
+		{pendulumText, "Once per turn.", "Cannot be destroyed.\nBy battle.", true},
+		{"Scale effect.\n---\nMonster effect.", "Scale effect.", "Monster effect.", true},
+		{"[ Pendulum Effect ]\n-----\n[ Flavor Text ]\nA dragon.", "", "A dragon.", true},
+		{"Monster effect.", "", "Monster effect.", false},
+		{"Scale effect.\n--\nMonster effect.", "", "Scale effect.\n--\nMonster effect.", false},
+		{"Scale - effect.\nMonster effect.", "", "Scale - effect.\nMonster effect.", false},
+	}
+	for _, test := range tests {
+		pendulum, monster, ok := splitPendulumText(test.desc)
+		if pendulum != test.pendulum || monster != test.monster || ok != test.ok {
+			t.Errorf("splitPendulumText(%q) = %q, %q, %v, want %q, %q, %v", test.desc, pendulum, monster, ok, test.pendulum, test.monster, test.ok)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package database
+
+import "fmt"
+
+// systemStringsLimit is the first description that refers to a card string.
+const systemStringsLimit = 10000
+
+// Describer resolves card names and descriptions from a card database and
+// the system strings. It implements ocgcore.Resolver.
+type Describer struct {
+	Cards   CardDatabase
+	Strings *Strings
+	Locale  string
+}
+
+func (d Describer) CardName(code uint32) string {
+	if name := d.Cards.Name(code, d.Locale); name != "" {
+		return name
+	}
+	return fmt.Sprintf("#%d", code)
+}
+
+func (d Describer) Describe(desc uint64) string {
+	if desc < systemStringsLimit {
+		if d.Strings != nil {
+			if s, ok := d.Strings.System[uint32(desc)]; ok {
+				return s
+			}
+		}
+		return fmt.Sprintf("string %d", desc)
+	}
+
+	code, index := uint32(desc>>4), int(desc&0xf)
+	if card, ok := d.Cards[code]; ok {
+		if s := card.LocalizedStr(index, d.Locale); s != "" {
+			return s
+		}
+	}
+	return fmt.Sprintf("effect %d", index+1)
+}
+
+func (d Describer) VictoryName(reason int) string {
+	if d.Strings == nil {
+		return ""
+	}
+	return d.Strings.VictoryName(reason)
+}
+
+func (d Describer) CounterName(counter int) string {
+	if d.Strings != nil {
+		if s := d.Strings.CounterName(counter); s != "" {
+			return s
+		}
+	}
+	return fmt.Sprintf("counter 0x%x", counter)
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Diff lists the card codes that changed between two databases.
+type Diff struct {
+	Added    []uint32 `json:"added"`
+	Removed  []uint32 `json:"removed"`
+	Modified []uint32 `json:"modified"`
+}
+
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Compare returns the changes from old to c. A card is modified when its
+// data or any of its texts changed.
+func (c CardDatabase) Compare(old CardDatabase) Diff {
+	diff := Diff{Added: []uint32{}, Removed: []uint32{}, Modified: []uint32{}}
+	for code, card := range c {
+		oldCard, ok := old[code]
+		if !ok {
+			diff.Added = append(diff.Added, code)
+			continue
+		}
+		if !sameEntry(card, oldCard) {
+			diff.Modified = append(diff.Modified, code)
+		}
+	}
+	for code := range old {
+		if _, ok := c[code]; !ok {
+			diff.Removed = append(diff.Removed, code)
+		}
+	}
+
+	for _, codes := range [][]uint32{diff.Added, diff.Removed, diff.Modified} {
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	}
+	return diff
+}
+
+func sameEntry(a, b *CardEntry) bool {
+	if a == b {
+		return true
+	}
+	return reflect.DeepEqual(a.Raw, b.Raw) &&
+		a.Name == b.Name &&
+		a.Description == b.Description &&
+		a.Pool == b.Pool &&
+		a.stringIndexes == b.stringIndexes &&
+		reflect.DeepEqual(a.strings, b.strings) &&
+		reflect.DeepEqual(a.locales, b.locales)
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// LocalizedText is the text of a card in one language.
+type LocalizedText struct {
+	Name        string
+	Description string
+	Strings     [16]string
+}
+
+// LoadLocale loads the texts table of a translated .cdb under a locale tag,
+// such as "ja" or "it". Texts of cards that aren't in the database are
+// ignored, so the cards must be loaded first.
+func (c CardDatabase) LoadLocale(fileName string, locale string) error {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", fileName))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`
+SELECT
+	id, name, desc, str1, str2, str3, str4, str5, str6, str7, str8, str9, str10, str11, str12, str13, str14, str15, str16
+FROM texts`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint32
+		var name, desc sql.NullString
+		var str [16]sql.NullString
+		err := rows.Scan(&id, &name, &desc, &str[0], &str[1], &str[2], &str[3], &str[4], &str[5], &str[6], &str[7],
+			&str[8], &str[9], &str[10], &str[11], &str[12], &str[13], &str[14], &str[15])
+		if err != nil {
+			return err
+		}
+
+		card, ok := c[id]
+		if !ok {
+			continue
+		}
+		text := &LocalizedText{
+			Name:        name.String,
+			Description: desc.String,
+		}
+		for i := range str {
+			text.Strings[i] = str[i].String
+		}
+		// entries can be shared between databases, don't modify the map
+		locales := make(map[string]*LocalizedText, len(card.locales)+1)
+		for l, t := range card.locales {
+			locales[l] = t
+		}
+		locales[locale] = text
+		card.locales = locales
+	}
+	return rows.Err()
+}
+
+func (c *CardEntry) localized(locale string) *LocalizedText {
+	if locale == "" {
+		return nil
+	}
+	return c.locales[locale]
+}
+
+// LocalizedName returns the name of a card in a language, falling back to
+// the default one.
+func (c *CardEntry) LocalizedName(locale string) string {
+	if t := c.localized(locale); t != nil && t.Name != "" {
+		return t.Name
+	}
+	return c.Name
+}
+
+func (c *CardEntry) LocalizedDescription(locale string) string {
+	if t := c.localized(locale); t != nil && t.Description != "" {
+		return t.Description
+	}
+	return c.Description
+}
+
+func (c *CardEntry) LocalizedStr(i int, locale string) string {
+	if t := c.localized(locale); t != nil && i >= 0 && i < len(t.Strings) && t.Strings[i] != "" {
+		return t.Strings[i]
+	}
+	return c.Str(i)
+}
+
+// Localized returns the card with its texts in a language.
+func (c *CardEntry) Localized(locale string) Card {
+	card := c.Card
+	if c.localized(locale) == nil {
+		return card
+	}
+
+	// the pendulum text is part of the description, copy it before setting
+	if card.Monster != nil && card.Monster.Pendulum != nil {
+		monster := *card.Monster
+		pendulum := *monster.Pendulum
+		monster.Pendulum = &pendulum
+		card.Monster = &monster
+	}
+	card.Name = c.LocalizedName(locale)
+	setCardText(&card, c.LocalizedDescription(locale))
+	return card
+}
+
+func (c CardDatabase) Name(code uint32, locale string) string {
+	card, ok := c[code]
+	if !ok {
+		return ""
+	}
+	return card.LocalizedName(locale)
+}
+
+func (c CardDatabase) Description(code uint32, locale string) string {
+	card, ok := c[code]
+	if !ok {
+		return ""
+	}
+	return card.LocalizedDescription(locale)
+}
+
+func (c CardDatabase) Card(code uint32, locale string) (Card, bool) {
+	card, ok := c[code]
+	if !ok {
+		return Card{}, false
+	}
+	return card.Localized(locale), true
+}
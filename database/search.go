@@ -0,0 +1,210 @@
+package database
+
+import (
+	"errors"
+	"ocgcore"
+	"ocgcore/lib"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+type SearchSort string
+
+const (
+	SearchSortName    SearchSort = "name"
+	SearchSortCode    SearchSort = "code"
+	SearchSortLevel   SearchSort = "level"
+	SearchSortAttack  SearchSort = "attack"
+	SearchSortDefense SearchSort = "defense"
+)
+
+// Range is an inclusive range of values.
+type Range struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+func (r *Range) contains(v int) bool {
+	return r == nil || (v >= r.Min && v <= r.Max)
+}
+
+// SearchQuery filters the cards of a database. Zero fields don't filter,
+// attributes and races match any of the listed ones.
+type SearchQuery struct {
+	Name        string                         `json:"name"`
+	Description string                         `json:"description"`
+	Type        *ocgcore.CardType              `json:"type"`
+	Frame       *ocgcore.CardMonsterFrame      `json:"frame"`
+	Attributes  []ocgcore.CardMonsterAttribute `json:"attributes"`
+	Races       []ocgcore.CardMonsterType      `json:"races"`
+	// Level matches the level, rank or link rating of monsters.
+	Level   *Range `json:"level"`
+	Attack  *Range `json:"attack"`
+	Defense *Range `json:"defense"`
+	Scale   *Range `json:"scale"`
+	// SetCode matches an archetype, including its sub-archetypes.
+	SetCode uint16 `json:"set_code"`
+	// Locale selects the language of the names and descriptions, both for
+	// filtering and in the results.
+	Locale string `json:"locale"`
+
+	Sort       SearchSort `json:"sort"`
+	Descending bool       `json:"descending"`
+	Offset     int        `json:"offset"`
+	Limit      int        `json:"limit"`
+}
+
+type SearchResultCard struct {
+	Code uint32 `json:"code"`
+	Card Card   `json:"card"`
+}
+
+type SearchResult struct {
+	Total int                `json:"total"`
+	Cards []SearchResultCard `json:"cards"`
+}
+
+type searchFilter struct {
+	q          *SearchQuery
+	name       string
+	words      []string
+	attributes lib.Attribute
+	races      lib.Race
+}
+
+func (f *searchFilter) match(card *CardEntry) bool {
+	q := f.q
+	raw := &card.Raw
+
+	if f.name != "" && !strings.Contains(strings.ToLower(card.LocalizedName(q.Locale)), f.name) {
+		return false
+	}
+	if len(f.words) > 0 {
+		desc := strings.ToLower(card.LocalizedDescription(q.Locale))
+		for _, w := range f.words {
+			if !strings.Contains(desc, w) {
+				return false
+			}
+		}
+	}
+	if q.Type != nil && card.Card.Type != *q.Type {
+		return false
+	}
+	if q.Frame != nil && (card.Card.Monster == nil || card.Card.Monster.Frame != *q.Frame) {
+		return false
+	}
+
+	monster := raw.Type&lib.CardTypeMonster != 0
+	if f.attributes != 0 && (!monster || raw.Attribute&f.attributes == 0) {
+		return false
+	}
+	if f.races != 0 && (!monster || raw.Race&f.races == 0) {
+		return false
+	}
+	if q.Level != nil && (!monster || !q.Level.contains(int(raw.Level))) {
+		return false
+	}
+	if q.Attack != nil && (!monster || !q.Attack.contains(int(raw.Attack))) {
+		return false
+	}
+	if q.Defense != nil && (!monster || raw.Type&lib.CardTypeLink != 0 || !q.Defense.contains(int(raw.Defense))) {
+		return false
+	}
+	if q.Scale != nil && (raw.Type&lib.CardTypePendulum == 0 || !q.Scale.contains(int(raw.LScale))) {
+		return false
+	}
+	if q.SetCode != 0 && !matchSetCode(raw.SetCodes, q.SetCode) {
+		return false
+	}
+	return true
+}
+
+// matchSetCode checks the archetype in the low 12 bits and, when set, the
+// sub-archetype in the high 4 bits.
+func matchSetCode(setCodes []uint16, setCode uint16) bool {
+	for _, s := range setCodes {
+		if s&0xfff == setCode&0xfff && s&setCode&0xf000 == setCode&0xf000 {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns a page of the cards matching a query.
+func (c CardDatabase) Search(q SearchQuery) (SearchResult, error) {
+	if q.Offset < 0 || q.Limit < 0 {
+		return SearchResult{}, errors.New("invalid pagination")
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > maxSearchLimit {
+		q.Limit = maxSearchLimit
+	}
+
+	f := searchFilter{
+		q:     &q,
+		name:  strings.ToLower(strings.TrimSpace(q.Name)),
+		words: strings.Fields(strings.ToLower(q.Description)),
+	}
+	for _, a := range q.Attributes {
+		f.attributes |= 1 << lib.Attribute(a)
+	}
+	for _, r := range q.Races {
+		f.races |= 1 << lib.Race(r)
+	}
+
+	var less func(a, b *CardEntry) bool
+	switch q.Sort {
+	case SearchSortName, "":
+		less = func(a, b *CardEntry) bool { return a.LocalizedName(q.Locale) < b.LocalizedName(q.Locale) }
+	case SearchSortCode:
+		less = func(a, b *CardEntry) bool { return false }
+	case SearchSortLevel:
+		less = func(a, b *CardEntry) bool { return a.Raw.Level < b.Raw.Level }
+	case SearchSortAttack:
+		less = func(a, b *CardEntry) bool { return a.Raw.Attack < b.Raw.Attack }
+	case SearchSortDefense:
+		less = func(a, b *CardEntry) bool { return a.Raw.Defense < b.Raw.Defense }
+	default:
+		return SearchResult{}, errors.New("invalid sort")
+	}
+
+	var cards []*CardEntry
+	for _, card := range c {
+		if f.match(card) {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		a, b := cards[i], cards[j]
+		if q.Descending {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Raw.Code < b.Raw.Code
+	})
+
+	result := SearchResult{Total: len(cards), Cards: []SearchResultCard{}}
+	if q.Offset >= len(cards) {
+		return result, nil
+	}
+	cards = cards[q.Offset:]
+	if len(cards) > q.Limit {
+		cards = cards[:q.Limit]
+	}
+	for _, card := range cards {
+		result.Cards = append(result.Cards, SearchResultCard{Code: card.Raw.Code, Card: card.Localized(q.Locale)})
+	}
+	return result, nil
+}
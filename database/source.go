@@ -0,0 +1,161 @@
+package database
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ocgcore"
+	"os"
+)
+
+// CardSource adds its cards to a database, replacing the ones with the same
+// code.
+type CardSource interface {
+	LoadCards(db CardDatabase) error
+}
+
+type sqliteSource string
+
+// SQLite is a .cdb card database.
+func SQLite(fileName string) CardSource {
+	return sqliteSource(fileName)
+}
+
+func (s sqliteSource) LoadCards(db CardDatabase) error {
+	return db.Load(string(s))
+}
+
+type localeSource struct {
+	fileName string
+	locale   string
+}
+
+// Locale adds the translated texts of a .cdb, see CardDatabase.LoadLocale.
+// Cards replaced by later sources lose their translations, so locales should
+// be merged last.
+func Locale(fileName string, locale string) CardSource {
+	return localeSource{fileName: fileName, locale: locale}
+}
+
+func (s localeSource) LoadCards(db CardDatabase) error {
+	return db.LoadLocale(s.fileName, s.locale)
+}
+
+// LoadCards copies the cards of an in-memory database.
+func (c CardDatabase) LoadCards(db CardDatabase) error {
+	for code, card := range c {
+		entry := *card
+		db[code] = &entry
+	}
+	return nil
+}
+
+// Add puts a card in the database, built from the same columns of a .cdb.
+func (c CardDatabase) Add(card JSONCard) {
+	c[card.ID] = card.toSelect().toEntry()
+}
+
+// JSONCard is a card in a JSON-lines source, with the fields of the datas and
+// texts tables of a .cdb.
+type JSONCard struct {
+	ID        uint32   `json:"id"`
+	Ot        uint32   `json:"ot"`
+	Alias     uint32   `json:"alias"`
+	SetCode   int64    `json:"setcode"`
+	Type      uint32   `json:"type"`
+	Atk       int32    `json:"atk"`
+	Def       int32    `json:"def"`
+	Level     int64    `json:"level"`
+	Race      uint32   `json:"race"`
+	Attribute uint32   `json:"attribute"`
+	Category  uint32   `json:"category"`
+	Name      string   `json:"name"`
+	Desc      string   `json:"desc"`
+	Str       []string `json:"str"`
+}
+
+func (j JSONCard) toSelect() *sqliteDatabaseSelect {
+	s := &sqliteDatabaseSelect{
+		dataId:        j.ID,
+		dataOt:        j.Ot,
+		dataAlias:     j.Alias,
+		dataType:      j.Type,
+		dataLevel:     j.Level,
+		dataRace:      j.Race,
+		dataAttribute: j.Attribute,
+		dataCategory:  j.Category,
+		dataSetCode:   j.SetCode,
+		dataAtk:       j.Atk,
+		dataDef:       j.Def,
+		name:          sql.NullString{String: j.Name, Valid: true},
+		desc:          sql.NullString{String: j.Desc, Valid: true},
+	}
+	for i := 0; i < len(j.Str) && i < len(s.str); i++ {
+		s.str[i] = sql.NullString{String: j.Str[i], Valid: true}
+	}
+	return s
+}
+
+type jsonLinesSource string
+
+// JSONLines is a file with a JSONCard on each line.
+func JSONLines(fileName string) CardSource {
+	return jsonLinesSource(fileName)
+}
+
+func (s jsonLinesSource) LoadCards(db CardDatabase) error {
+	f, err := os.Open(string(s))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := ReadJSONLines(db, f); err != nil {
+		return fmt.Errorf("%s: %w", string(s), err)
+	}
+	return nil
+}
+
+// ReadJSONLines adds the cards of a JSON-lines stream to a database.
+func ReadJSONLines(db CardDatabase, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var card JSONCard
+		if err := json.Unmarshal(scanner.Bytes(), &card); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		db.Add(card)
+	}
+	return scanner.Err()
+}
+
+// Merge builds a database from sources in order of precedence, the cards of
+// later sources override the earlier ones: base cards first, then releases,
+// then custom cards.
+func Merge(sources ...CardSource) (CardDatabase, error) {
+	db := NewDatabase()
+	for _, source := range sources {
+		if err := source.LoadCards(db); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// Reader returns a card reader for CreateDuelOptions.
+func (c CardDatabase) Reader() ocgcore.CardReader {
+	return func(code uint32) (raw ocgcore.RawCardData) {
+		if card, ok := c[code]; ok {
+			raw = card.Raw
+		}
+		return
+	}
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"ocgcore"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Strings holds the tables of a strings.conf file: system strings, victory
+// reasons, counter names and archetype names.
+type Strings struct {
+	System  map[uint32]string
+	Victory map[uint32]string
+	Counter map[uint32]string
+	SetName map[uint16]string
+}
+
+func NewStrings() *Strings {
+	return &Strings{
+		System:  map[uint32]string{},
+		Victory: map[uint32]string{},
+		Counter: map[uint32]string{},
+		SetName: map[uint16]string{},
+	}
+}
+
+func (s *Strings) Load(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := s.Read(f); err != nil {
+		return fmt.Errorf("%s: %w", fileName, err)
+	}
+	return nil
+}
+
+// Read parses strings.conf entries, later entries override earlier ones so
+// that more files can be loaded on top of each other.
+func (s *Strings) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if !strings.HasPrefix(text, "!") {
+			continue
+		}
+
+		parts := strings.SplitN(text, " ", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		kind, value, name := parts[0], parts[1], parts[2]
+
+		// setnames can have the original name after a tab
+		if i := strings.IndexByte(name, '\t'); i >= 0 {
+			name = name[:i]
+		}
+		name = strings.TrimSpace(name)
+
+		var table map[uint32]string
+		switch kind {
+		case "!system":
+			table = s.System
+		case "!victory":
+			table = s.Victory
+		case "!counter":
+			table = s.Counter
+		case "!setname":
+			v, err := strconv.ParseUint(value, 0, 16)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			s.SetName[uint16(v)] = name
+			continue
+		default:
+			continue
+		}
+
+		v, err := strconv.ParseUint(value, 0, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		table[uint32(v)] = name
+	}
+	return scanner.Err()
+}
+
+// Archetypes returns the names of the archetypes of a card. A sub-archetype,
+// whose high 4 bits refine the archetype in the low 12 bits, also belongs to
+// its parent archetype.
+func (s *Strings) Archetypes(setCodes []uint16) []string {
+	var names []string
+	seen := map[uint16]bool{}
+	add := func(setCode uint16) {
+		if seen[setCode] {
+			return
+		}
+		seen[setCode] = true
+		if name, ok := s.SetName[setCode]; ok {
+			names = append(names, name)
+		}
+	}
+
+	for _, setCode := range setCodes {
+		add(setCode)
+		add(setCode & 0xfff)
+	}
+	return names
+}
+
+func (s *Strings) VictoryName(reason int) string {
+	return s.Victory[uint32(reason)]
+}
+
+func (s *Strings) CounterName(counter int) string {
+	return s.Counter[uint32(counter)]
+}
+
+// WinReason returns the name of the reason a duel was won.
+func (s *Strings) WinReason(m ocgcore.MessageWin) string {
+	return s.VictoryName(m.Reason)
+}
+
+// CounterType returns the name of the counter to select.
+func (s *Strings) CounterType(m ocgcore.MessageSelectCounter) string {
+	return s.CounterName(m.CounterType)
+}
+
+// SetArchetypes fills the archetype names of every card.
+func (c CardDatabase) SetArchetypes(s *Strings) {
+	for _, card := range c {
+		card.Card.Archetypes = s.Archetypes(card.Raw.SetCodes)
+	}
+}
@@ -0,0 +1,135 @@
+package ocgcore
+
+import (
+	"fmt"
+	"ocgcore/lib"
+	"sync"
+	"time"
+)
+
+// maxDiagnosticsLog is the number of log entries kept for each duel, both
+// for the whole log and for the errors alone.
+const maxDiagnosticsLog = 256
+
+type ScriptStatus string
+
+const (
+	ScriptLoaded  ScriptStatus = "loaded"
+	ScriptMissing ScriptStatus = "missing"
+	ScriptFailed  ScriptStatus = "failed"
+)
+
+// ScriptLoad is an attempt of the core to load a script.
+type ScriptLoad struct {
+	Name   string       `json:"name"`
+	Status ScriptStatus `json:"status"`
+	// Error is the last message logged by the core while loading a failed
+	// script, usually the Lua error.
+	Error string `json:"error,omitempty"`
+}
+
+// Diagnostics reports the scripts loaded by a duel and the messages logged
+// by the core.
+type Diagnostics struct {
+	Scripts []ScriptLoad `json:"scripts"`
+	Loaded  int          `json:"loaded"`
+	Missing int          `json:"missing"`
+	Failed  int          `json:"failed"`
+	// Log and Errors hold the latest entries, up to maxDiagnosticsLog each.
+	Log    []LogEntry `json:"log"`
+	Errors []LogEntry `json:"errors"`
+}
+
+type diagnostics struct {
+	duelID  uint64
+	handler LogHandler
+
+	lock    sync.Mutex
+	scripts []ScriptLoad
+	log     []LogEntry
+	errors  []LogEntry
+	logged  int
+	lastLog string
+}
+
+func appendLog(entries []LogEntry, entry LogEntry) []LogEntry {
+	if len(entries) == maxDiagnosticsLog {
+		copy(entries, entries[1:])
+		entries = entries[:len(entries)-1]
+	}
+	return append(entries, entry)
+}
+
+func (d *diagnostics) addLog(typ LogType, message string) {
+	entry := LogEntry{
+		DuelID:  d.duelID,
+		Type:    typ,
+		Message: message,
+		Time:    time.Now(),
+	}
+
+	d.lock.Lock()
+	d.logged++
+	d.lastLog = message
+	d.log = appendLog(d.log, entry)
+	if typ == LogTypeError {
+		d.errors = appendLog(d.errors, entry)
+	}
+	d.lock.Unlock()
+
+	if d.handler != nil {
+		d.handler(entry)
+	}
+}
+
+// loadScript reads and loads a script, recording the outcome. The core may
+// load other scripts and log while running it, so the lock isn't held.
+func (d *diagnostics) loadScript(backend Backend, duel lib.Duel, reader ScriptReader, name string) ScriptLoad {
+	load := ScriptLoad{Name: name, Status: ScriptLoaded}
+
+	contents := reader(name)
+	if len(contents) == 0 {
+		load.Status = ScriptMissing
+		d.addLog(LogTypeForDebug, fmt.Sprintf("script not found: %s", name))
+	} else {
+		d.lock.Lock()
+		logged := d.logged
+		d.lock.Unlock()
+
+		if !backend.LoadScript(duel, contents, name) {
+			load.Status = ScriptFailed
+			d.lock.Lock()
+			if d.logged > logged {
+				load.Error = d.lastLog
+			}
+			d.lock.Unlock()
+		}
+	}
+
+	d.lock.Lock()
+	d.scripts = append(d.scripts, load)
+	d.lock.Unlock()
+	return load
+}
+
+func (d *diagnostics) report() Diagnostics {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	r := Diagnostics{
+		Scripts: append([]ScriptLoad{}, d.scripts...),
+		Log:     append([]LogEntry{}, d.log...),
+		Errors:  append([]LogEntry{}, d.errors...),
+	}
+	for _, s := range d.scripts {
+		switch s.Status {
+		case ScriptLoaded:
+			r.Loaded++
+		case ScriptMissing:
+			r.Missing++
+		case ScriptFailed:
+			r.Failed++
+		}
+	}
+	return r
+}
@@ -1,13 +1,22 @@
 package ocgcore
 
 import (
+	"fmt"
 	"math/rand"
 	"ocgcore/lib"
 	"sync"
 )
 
 type OcgDuel struct {
-	handle lib.Duel
+	id       uint64
+	backend  Backend
+	handle   lib.Duel
+	teams    [2]lib.Player
+	teamSize [2]int
+	format   Format
+	pending  []Message
+
+	diagnostics *diagnostics
 
 	messageCh  chan Message
 	incomingCh chan []byte
@@ -15,13 +24,46 @@ type OcgDuel struct {
 	aliveLock sync.Mutex
 }
 
-func newDuel(d lib.Duel) *OcgDuel {
-	return &OcgDuel{handle: d}
+func newDuel(backend Backend, d lib.Duel, options lib.DuelOptions, format Format, teamSize [2]int) *OcgDuel {
+	for i := range teamSize {
+		if teamSize[i] <= 0 {
+			teamSize[i] = 1
+		}
+	}
+	return &OcgDuel{
+		backend:  backend,
+		handle:   d,
+		teams:    [2]lib.Player{options.Team1, options.Team2},
+		teamSize: teamSize,
+		format:   format,
+	}
+}
+
+// ID identifies the duel in its log entries.
+func (d *OcgDuel) ID() uint64 {
+	return d.id
+}
+
+func (d *OcgDuel) Format() Format {
+	return d.format
+}
+
+func (d *OcgDuel) FieldStatus() (Field, error) {
+	return fieldStatus(d.backend, d.handle, d.format)
+}
+
+// Diagnostics reports the scripts loaded so far and the core log.
+func (d *OcgDuel) Diagnostics() Diagnostics {
+	return d.diagnostics.report()
+}
+
+func (d *OcgDuel) TeamSize(team int) int {
+	return d.teamSize[team]
 }
 
 func (d *OcgDuel) Destroy() {
 	close(d.incomingCh)
-	lib.DestroyDuel(d.handle)
+	d.backend.DestroyDuel(d.handle)
 }
 
 func (d *OcgDuel) Start() <-chan Message {
@@ -34,11 +76,11 @@ func (d *OcgDuel) Start() <-chan Message {
 
 func (d *OcgDuel) run() {
 	d.readMessages()
-	lib.StartDuel(d.handle)
+	d.backend.StartDuel(d.handle)
 
 outer:
 	for {
-		status := lib.DuelProcess(d.handle)
+		status := d.backend.Process(d.handle)
 		d.readMessages()
 		switch status {
 		case lib.ProcessorFlagEnd:
@@ -50,7 +92,7 @@ outer:
 				return
 			}
 			if r != nil {
-				lib.DuelSetResponse(d.handle, r)
+				d.backend.SetResponse(d.handle, r)
 			}
 		case lib.ProcessorFlagContinue:
 			continue
@@ -70,10 +112,25 @@ func (d *OcgDuel) readMessages() {
 	d.aliveLock.Lock()
 	defer d.aliveLock.Unlock()
 
-	messages := duelGetMessage(d.handle)
-	for _, message := range messages {
+	pending := d.pending
+	d.pending = nil
+	for _, message := range pending {
 		if d.messageCh != nil {
-			d.messageCh <- readMessage(message)
+			d.messageCh <- message
+		}
+	}
+
+	messages, err := duelGetMessage(d.backend, d.handle)
+	if err != nil {
+		d.diagnostics.addLog(LogTypeError, fmt.Sprintf("reading messages: %v", err))
+	}
+	for _, message := range messages {
+		msg, err := readMessage(message)
+		if err != nil {
+			d.diagnostics.addLog(LogTypeError, err.Error())
+		}
+		if d.messageCh != nil && msg != nil {
+			d.messageCh <- msg
 		}
 	}
 }
@@ -83,6 +140,16 @@ func (d *OcgDuel) SendResponse(r Response) {
 }
 
 func (d *OcgDuel) SetupDeck(player int, mainDeck []uint32, extraDeck []uint32, shuffle bool) {
+	d.SetupDuelistDeck(player, 0, mainDeck, extraDeck, shuffle)
+}
+
+// SetupDuelistDeck sets the deck of one duelist of a team. Duelist 0 is the
+// one starting the duel, the others enter on tag swaps or, in relay duels,
+// when the previous duelist loses.
+func (d *OcgDuel) SetupDuelistDeck(team int, duelist int, mainDeck []uint32, extraDeck []uint32, shuffle bool) {
+	if duelist < 0 || duelist >= d.teamSize[team] {
+		panic("invalid duelist")
+	}
 	if shuffle {
 		rand.Shuffle(len(mainDeck), func(i, j int) {
 			mainDeck[i], mainDeck[j] = mainDeck[j], mainDeck[i]
@@ -90,20 +157,20 @@ func (d *OcgDuel) SetupDeck(player int, mainDeck []uint32, extraDeck []uint32, s
 	}
 
 	var cardInfo lib.NewCardInfo
-	cardInfo.Duelist = 0
-	cardInfo.Team = uint8(player)
-	cardInfo.Controller = uint8(player)
+	cardInfo.Duelist = uint8(duelist)
+	cardInfo.Team = uint8(team)
+	cardInfo.Controller = uint8(team)
 	cardInfo.Position = lib.PositionFaceDownDefense
 
 	cardInfo.Location = lib.LocationDeck
 	for _, card := range mainDeck {
 		cardInfo.Code = card
-		lib.DuelNewCard(d.handle, cardInfo)
+		d.backend.NewCard(d.handle, cardInfo)
 	}
 
 	cardInfo.Location = lib.LocationExtra
 	for _, card := range extraDeck {
 		cardInfo.Code = card
-		lib.DuelNewCard(d.handle, cardInfo)
+		d.backend.NewCard(d.handle, cardInfo)
 	}
 }
@@ -0,0 +1,251 @@
+// Package fake is a backend that plays back predefined messages instead of
+// running the core, so that code driving duels can be tested without cgo,
+// scripts or a card database.
+//
+//	backend := fake.New(
+//		fake.Step{Messages: [][]byte{fake.Message(lib.MessageSelectYesNo, uint8(0), uint64(30))}, Wait: true},
+//		fake.Step{Messages: [][]byte{fake.Message(lib.MessageWin, uint8(0), uint8(0))}},
+//	)
+//	duel, _ := ocgcore.CreateDuel(ocgcore.CreateDuelOptions{
+//		Backend:      backend,
+//		CardReader:   fake.CardReader,
+//		ScriptReader: fake.ScriptReader,
+//	})
+//
+// Every duel created by a backend plays the same steps, the cards, scripts and
+// responses it received can be inspected with Duels.
+package fake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"ocgcore"
+	"ocgcore/lib"
+	"sync"
+)
+
+// Step is a call to process the duel. It produces its messages, then waits for
+// a response when Wait is set.
+type Step struct {
+	Messages [][]byte
+	Wait     bool
+}
+
+// Message encodes a message from its type and its fields, which must be fixed
+// size values, or slices of them, accepted by binary.Write.
+func Message(typ lib.Message, fields ...interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteByte(byte(typ))
+	for _, f := range fields {
+		if err := binary.Write(&b, binary.LittleEndian, f); err != nil {
+			panic(err)
+		}
+	}
+	return b.Bytes()
+}
+
+// CardReader returns a card with only its code.
+func CardReader(code uint32) ocgcore.RawCardData {
+	return ocgcore.RawCardData{Code: code}
+}
+
+// ScriptReader returns a placeholder for any script, the fake backend doesn't
+// run them.
+func ScriptReader(name string) []byte {
+	return []byte("-- " + name)
+}
+
+// Duel is the state of a fake duel.
+type Duel struct {
+	Options   lib.DuelOptions
+	Cards     []lib.NewCardInfo
+	Scripts   []string
+	Sources   [][]byte // the contents of Scripts
+	Responses [][]byte
+	Started   bool
+	Destroyed bool
+
+	step    int
+	waiting bool
+	pending [][]byte
+}
+
+type Backend struct {
+	// APIVersion is the version reported, ocgcore.MessageProtocol when zero.
+	APIVersion ocgcore.ProtocolVersion
+	// Queries answers the card and location queries, which find no cards
+	// when nil.
+	Queries func(duel *Duel, info lib.QueryInfo) []byte
+	// Field answers the field queries.
+	Field func(duel *Duel) []byte
+
+	steps []Step
+
+	lock  sync.Mutex
+	last  lib.Duel
+	duels map[lib.Duel]*Duel
+	order []*Duel
+}
+
+func New(steps ...Step) *Backend {
+	return &Backend{
+		steps: steps,
+		duels: map[lib.Duel]*Duel{},
+	}
+}
+
+// Duels returns a copy of the duels created so far, in order.
+func (b *Backend) Duels() []Duel {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	duels := make([]Duel, len(b.order))
+	for i, d := range b.order {
+		duels[i] = *d
+		duels[i].Cards = append([]lib.NewCardInfo{}, d.Cards...)
+		duels[i].Scripts = append([]string{}, d.Scripts...)
+		duels[i].Sources = append([][]byte{}, d.Sources...)
+		duels[i].Responses = append([][]byte{}, d.Responses...)
+		duels[i].pending = nil
+	}
+	return duels
+}
+
+// with calls fn holding the lock.
+func (b *Backend) with(duel lib.Duel, fn func(d *Duel)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	d, ok := b.duels[duel]
+	if !ok || d.Destroyed {
+		panic("fake: invalid duel")
+	}
+	fn(d)
+}
+
+func (b *Backend) Version() (ocgcore.ProtocolVersion, error) {
+	if b.APIVersion == (ocgcore.ProtocolVersion{}) {
+		return ocgcore.MessageProtocol, nil
+	}
+	return b.APIVersion, nil
+}
+
+func (b *Backend) CreateDuel(options lib.DuelOptions) (lib.Duel, error) {
+	if options.CardReader == nil || options.ScriptReader == nil {
+		return 0, errors.New("fake: missing reader")
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.last++
+	d := &Duel{Options: options}
+	b.duels[b.last] = d
+	b.order = append(b.order, d)
+	return b.last, nil
+}
+
+func (b *Backend) DestroyDuel(duel lib.Duel) {
+	b.with(duel, func(d *Duel) {
+		d.Destroyed = true
+	})
+}
+
+// NewCard reads the card data, as the core does.
+func (b *Backend) NewCard(duel lib.Duel, info lib.NewCardInfo) {
+	var options lib.DuelOptions
+	b.with(duel, func(d *Duel) {
+		options = d.Options
+		d.Cards = append(d.Cards, info)
+	})
+
+	data := options.CardReader(info.Code)
+	if options.CardReaderDone != nil {
+		options.CardReaderDone(data)
+	}
+}
+
+func (b *Backend) LoadScript(duel lib.Duel, buffer []byte, name string) bool {
+	if len(buffer) == 0 {
+		return false
+	}
+	b.with(duel, func(d *Duel) {
+		d.Scripts = append(d.Scripts, name)
+		d.Sources = append(d.Sources, append([]byte{}, buffer...))
+	})
+	return true
+}
+
+func (b *Backend) StartDuel(duel lib.Duel) {
+	b.with(duel, func(d *Duel) {
+		d.Started = true
+	})
+}
+
+func (b *Backend) Process(duel lib.Duel) (flag lib.ProcessorFlag) {
+	b.with(duel, func(d *Duel) {
+		if !d.Started {
+			panic("fake: duel not started")
+		}
+		if d.waiting {
+			panic("fake: processing without a response")
+		}
+		if d.step >= len(b.steps) {
+			flag = lib.ProcessorFlagEnd
+			return
+		}
+		step := b.steps[d.step]
+		d.step++
+		d.pending = append(d.pending, step.Messages...)
+		d.waiting = step.Wait
+		if step.Wait {
+			flag = lib.ProcessorFlagWaiting
+		} else {
+			flag = lib.ProcessorFlagContinue
+		}
+	})
+	return
+}
+
+func (b *Backend) GetMessage(duel lib.Duel) []byte {
+	var buf bytes.Buffer
+	b.with(duel, func(d *Duel) {
+		for _, m := range d.pending {
+			_ = binary.Write(&buf, binary.LittleEndian, uint32(len(m)))
+			buf.Write(m)
+		}
+		d.pending = nil
+	})
+	return buf.Bytes()
+}
+
+func (b *Backend) SetResponse(duel lib.Duel, response []byte) {
+	b.with(duel, func(d *Duel) {
+		if !d.waiting {
+			panic("fake: unexpected response")
+		}
+		d.waiting = false
+		d.Responses = append(d.Responses, append([]byte{}, response...))
+	})
+}
+
+func (b *Backend) Query(duel lib.Duel, info lib.QueryInfo) (data []byte) {
+	b.with(duel, func(d *Duel) {
+		if b.Queries != nil {
+			data = b.Queries(d, info)
+		}
+	})
+	return
+}
+
+func (b *Backend) QueryLocation(duel lib.Duel, info lib.QueryInfo) []byte {
+	return b.Query(duel, info)
+}
+
+func (b *Backend) QueryField(duel lib.Duel) (data []byte) {
+	b.with(duel, func(d *Duel) {
+		if b.Field != nil {
+			data = b.Field(d)
+		}
+	})
+	return
+}
@@ -0,0 +1,198 @@
+package ocgcore
+
+import (
+	"fmt"
+	"ocgcore/lib"
+)
+
+// CardPool is the set of scopes a card is legal in, as stored in the ot
+// column of the card database.
+type CardPool uint32
+
+const (
+	CardPoolOCG CardPool = 1 << iota
+	CardPoolTCG
+	CardPoolAnime
+	CardPoolIllegal
+	CardPoolVideoGame
+	CardPoolCustom
+	CardPoolSpeed
+	_
+	CardPoolPrerelease
+	CardPoolRush
+	CardPoolLegend
+)
+
+// DeckCard is what deck validation needs to know about a card.
+type DeckCard struct {
+	Pool CardPool
+	// Alias is the code of the card this one is an alternate artwork of,
+	// zero for original cards. Copies are counted by alias.
+	Alias uint32
+}
+
+// Format bundles the rules of a duel format: the core flags, the starting
+// state of the players, the deck building restrictions and the zone layout
+// that follows from the flags.
+type Format struct {
+	Name       string
+	Flags      lib.DuelMode
+	StartingLP int
+	HandSize   int
+	DrawCount  int
+
+	MainDeckMin  int
+	MainDeckMax  int
+	ExtraDeckMax int
+	MaxCopies    int
+	CardPool     CardPool
+}
+
+var (
+	FormatMasterRule = Format{
+		Name:         "master_rule",
+		Flags:        lib.DuelModeMR5,
+		StartingLP:   8000,
+		HandSize:     5,
+		DrawCount:    1,
+		MainDeckMin:  40,
+		MainDeckMax:  60,
+		ExtraDeckMax: 15,
+		MaxCopies:    3,
+		CardPool:     CardPoolOCG | CardPoolTCG,
+	}
+	FormatGoat = Format{
+		Name:         "goat",
+		Flags:        lib.DuelModeGoat,
+		StartingLP:   8000,
+		HandSize:     5,
+		DrawCount:    1,
+		MainDeckMin:  40,
+		MainDeckMax:  60,
+		ExtraDeckMax: 15,
+		MaxCopies:    3,
+		CardPool:     CardPoolOCG | CardPoolTCG,
+	}
+	FormatSpeed = Format{
+		Name:         "speed",
+		Flags:        lib.DuelModeSpeed,
+		StartingLP:   4000,
+		HandSize:     4,
+		DrawCount:    1,
+		MainDeckMin:  20,
+		MainDeckMax:  30,
+		ExtraDeckMax: 5,
+		MaxCopies:    3,
+		CardPool:     CardPoolSpeed,
+	}
+	FormatRush = Format{
+		Name:         "rush",
+		Flags:        lib.DuelModeRush,
+		StartingLP:   8000,
+		HandSize:     4,
+		DrawCount:    1,
+		MainDeckMin:  40,
+		MainDeckMax:  60,
+		ExtraDeckMax: 15,
+		MaxCopies:    3,
+		CardPool:     CardPoolRush | CardPoolLegend,
+	}
+)
+
+// FormatForMode returns the format matching one of the duel mode presets.
+// Modes without a dedicated format use the master rule restrictions.
+func FormatForMode(mode DuelMode) Format {
+	switch mode {
+	case DuelModeSpeed:
+		return FormatSpeed
+	case DuelModeRush:
+		return FormatRush
+	case DuelModeGoat:
+		return FormatGoat
+	}
+
+	f := FormatMasterRule
+	switch mode {
+	case DuelModeMR1:
+		f.Name, f.Flags = "master_rule_1", lib.DuelModeMR1
+	case DuelModeMR2:
+		f.Name, f.Flags = "master_rule_2", lib.DuelModeMR2
+	case DuelModeMR3:
+		f.Name, f.Flags = "master_rule_3", lib.DuelModeMR3
+	case DuelModeMR4:
+		f.Name, f.Flags = "master_rule_4", lib.DuelModeMR4
+	}
+	return f
+}
+
+// FormatByName returns one of the preset formats.
+func FormatByName(name string) (Format, bool) {
+	for _, f := range []Format{FormatMasterRule, FormatGoat, FormatSpeed, FormatRush} {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Columns returns the number of main monster and spell & trap zones.
+func (f Format) Columns() int {
+	if f.Flags&lib.Duel3ColumnsField != 0 {
+		return 3
+	}
+	return 5
+}
+
+// columnSequences returns the zone sequences in use, the 3 columns field
+// only uses the middle ones.
+func (f Format) columnSequences() []int {
+	if f.Columns() == 3 {
+		return []int{1, 2, 3}
+	}
+	return []int{0, 1, 2, 3, 4}
+}
+
+func (f Format) ExtraMonsterZones() bool {
+	return f.Flags&lib.DuelEMZone != 0
+}
+
+// SeparatePendulumZones reports whether pendulum zones are distinct from the
+// spell & trap zones, as in master rule 3.
+func (f Format) SeparatePendulumZones() bool {
+	return f.Flags&lib.DuelSeparatePZone != 0
+}
+
+// ValidateDeck checks a deck against the format restrictions. cards returns
+// the card pool and alias of a card, and false if the card doesn't exist.
+func (f Format) ValidateDeck(mainDeck []uint32, extraDeck []uint32, cards func(code uint32) (DeckCard, bool)) error {
+	if len(mainDeck) < f.MainDeckMin || len(mainDeck) > f.MainDeckMax {
+		return fmt.Errorf("main deck must have between %d and %d cards, has %d", f.MainDeckMin, f.MainDeckMax, len(mainDeck))
+	}
+	if len(extraDeck) > f.ExtraDeckMax {
+		return fmt.Errorf("extra deck must have at most %d cards, has %d", f.ExtraDeckMax, len(extraDeck))
+	}
+
+	copies := map[uint32]int{}
+	for _, deck := range [][]uint32{mainDeck, extraDeck} {
+		for _, code := range deck {
+			card, ok := cards(code)
+			if !ok {
+				return fmt.Errorf("card %d: not found", code)
+			}
+
+			original := code
+			if card.Alias != 0 {
+				original = card.Alias
+			}
+			copies[original]++
+			if copies[original] > f.MaxCopies {
+				return fmt.Errorf("card %d: more than %d copies", code, f.MaxCopies)
+			}
+
+			if f.CardPool != 0 && card.Pool&f.CardPool == 0 {
+				return fmt.Errorf("card %d: not legal in %s", code, f.Name)
+			}
+		}
+	}
+	return nil
+}
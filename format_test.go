@@ -0,0 +1,33 @@
+package ocgcore_test
+
+import (
+	"ocgcore"
+	"strings"
+	"testing"
+)
+
+func TestValidateDeckAliases(t *testing.T) {
+	format := ocgcore.Format{Name: "test", MainDeckMax: 10, ExtraDeckMax: 5, MaxCopies: 2}
+	// 2 is an alternate artwork of 1
+	cards := func(code uint32) (ocgcore.DeckCard, bool) {
+		switch code {
+		case 1, 3:
+			return ocgcore.DeckCard{}, true
+		case 2:
+			return ocgcore.DeckCard{Alias: 1}, true
+		}
+		return ocgcore.DeckCard{}, false
+	}
+
+	if err := format.ValidateDeck([]uint32{1, 2, 3, 3}, nil, cards); err != nil {
+		t.Fatal(err)
+	}
+	err := format.ValidateDeck([]uint32{1, 3}, []uint32{2, 1}, cards)
+	if err == nil || !strings.Contains(err.Error(), "card 1: more than 2 copies") {
+		t.Fatalf("got error %v", err)
+	}
+	err = format.ValidateDeck([]uint32{1, 4}, nil, cards)
+	if err == nil || !strings.Contains(err.Error(), "card 4: not found") {
+		t.Fatalf("got error %v", err)
+	}
+}
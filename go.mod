@@ -1,6 +1,6 @@
 module ocgcore
 
-go 1.13
+go 1.18
 
 require (
 	github.com/gorilla/websocket v1.4.2
@@ -9,3 +9,8 @@ require (
 	golang.org/x/sys v0.0.0-20201013132646-2da7054afaeb
 	golang.org/x/tools v0.0.0-20201014231627-1610a49f37af
 )
+
+require (
+	golang.org/x/mod v0.3.0 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+)
@@ -132,6 +132,15 @@ const (
 	ProcessorFlagContinue
 )
 
+type LogType int
+
+const (
+	LogTypeError LogType = iota
+	LogTypeFromScript
+	LogTypeForDebug
+	LogTypeUndefined
+)
+
 type CardType uint32
 
 const (
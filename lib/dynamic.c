@@ -0,0 +1,114 @@
+// +build ocgcore_dynamic
+
+// Trampolines to the functions of the core shared library, loaded by
+// ocgcore_load. They have the same names as the functions of the static core,
+// so the bindings don't depend on how the core is linked.
+
+#include <dlfcn.h>
+#include <stddef.h>
+#include "ocgapi.h"
+
+static void* handle;
+
+static void (*p_OCG_GetVersion)(int*, int*);
+static int (*p_OCG_CreateDuel)(OCG_Duel*, OCG_DuelOptions);
+static void (*p_OCG_DestroyDuel)(OCG_Duel);
+static void (*p_OCG_DuelNewCard)(OCG_Duel, OCG_NewCardInfo);
+static void (*p_OCG_StartDuel)(OCG_Duel);
+static int (*p_OCG_DuelProcess)(OCG_Duel);
+static void* (*p_OCG_DuelGetMessage)(OCG_Duel, uint32_t*);
+static void (*p_OCG_DuelSetResponse)(OCG_Duel, const void*, uint32_t);
+static int (*p_OCG_LoadScript)(OCG_Duel, const char*, uint32_t, const char*);
+static uint32_t (*p_OCG_DuelQueryCount)(OCG_Duel, uint8_t, uint32_t);
+static void* (*p_OCG_DuelQuery)(OCG_Duel, uint32_t*, OCG_QueryInfo);
+static void* (*p_OCG_DuelQueryLocation)(OCG_Duel, uint32_t*, OCG_QueryInfo);
+static void* (*p_OCG_DuelQueryField)(OCG_Duel, uint32_t*);
+
+#define LOAD(name)                                  \
+	*(void**)(&p_##name) = dlsym(handle, #name);    \
+	if (p_##name == NULL) {                         \
+		const char* err = dlerror();                \
+		ocgcore_unload();                           \
+		return err != NULL ? err : "missing " #name; \
+	}
+
+void ocgcore_unload(void) {
+	if (handle != NULL) {
+		dlclose(handle);
+		handle = NULL;
+	}
+}
+
+// ocgcore_load returns NULL on success, the error otherwise.
+const char* ocgcore_load(const char* path) {
+	handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (handle == NULL) {
+		return dlerror();
+	}
+	LOAD(OCG_GetVersion)
+	LOAD(OCG_CreateDuel)
+	LOAD(OCG_DestroyDuel)
+	LOAD(OCG_DuelNewCard)
+	LOAD(OCG_StartDuel)
+	LOAD(OCG_DuelProcess)
+	LOAD(OCG_DuelGetMessage)
+	LOAD(OCG_DuelSetResponse)
+	LOAD(OCG_LoadScript)
+	LOAD(OCG_DuelQueryCount)
+	LOAD(OCG_DuelQuery)
+	LOAD(OCG_DuelQueryLocation)
+	LOAD(OCG_DuelQueryField)
+	return NULL;
+}
+
+void OCG_GetVersion(int* major, int* minor) {
+	p_OCG_GetVersion(major, minor);
+}
+
+int OCG_CreateDuel(OCG_Duel* duel, OCG_DuelOptions options) {
+	return p_OCG_CreateDuel(duel, options);
+}
+
+void OCG_DestroyDuel(OCG_Duel duel) {
+	p_OCG_DestroyDuel(duel);
+}
+
+void OCG_DuelNewCard(OCG_Duel duel, OCG_NewCardInfo info) {
+	p_OCG_DuelNewCard(duel, info);
+}
+
+void OCG_StartDuel(OCG_Duel duel) {
+	p_OCG_StartDuel(duel);
+}
+
+int OCG_DuelProcess(OCG_Duel duel) {
+	return p_OCG_DuelProcess(duel);
+}
+
+void* OCG_DuelGetMessage(OCG_Duel duel, uint32_t* length) {
+	return p_OCG_DuelGetMessage(duel, length);
+}
+
+void OCG_DuelSetResponse(OCG_Duel duel, const void* buffer, uint32_t length) {
+	p_OCG_DuelSetResponse(duel, buffer, length);
+}
+
+int OCG_LoadScript(OCG_Duel duel, const char* buffer, uint32_t length, const char* name) {
+	return p_OCG_LoadScript(duel, buffer, length, name);
+}
+
+uint32_t OCG_DuelQueryCount(OCG_Duel duel, uint8_t team, uint32_t loc) {
+	return p_OCG_DuelQueryCount(duel, team, loc);
+}
+
+void* OCG_DuelQuery(OCG_Duel duel, uint32_t* length, OCG_QueryInfo info) {
+	return p_OCG_DuelQuery(duel, length, info);
+}
+
+void* OCG_DuelQueryLocation(OCG_Duel duel, uint32_t* length, OCG_QueryInfo info) {
+	return p_OCG_DuelQueryLocation(duel, length, info);
+}
+
+void* OCG_DuelQueryField(OCG_Duel duel, uint32_t* length) {
+	return p_OCG_DuelQueryField(duel, length);
+}
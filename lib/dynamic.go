@@ -0,0 +1,67 @@
+//go:build ocgcore_dynamic
+// +build ocgcore_dynamic
+
+package lib
+
+/*
+#cgo LDFLAGS: -ldl
+#include "ocgapi.h"
+#include <stdlib.h>
+extern const char* ocgcore_load(const char* path);
+extern void ocgcore_unload(void);
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// DefaultLibrary is loaded when Load is called without a path and the
+// OCGCORE_LIBRARY environment variable is empty.
+const DefaultLibrary = "libocgcore.so"
+
+var loadLock sync.Mutex
+var loadedPath string
+
+// Load opens the core shared library and checks that its API version matches
+// the one the bindings were built for. It must succeed before any duel is
+// created. Once loaded, the core can't be replaced: later calls succeed only
+// for the same path or an empty one.
+func Load(path string) error {
+	loadLock.Lock()
+	defer loadLock.Unlock()
+
+	if path == "" && loadedPath != "" {
+		return nil
+	}
+	if path == "" {
+		path = os.Getenv("OCGCORE_LIBRARY")
+	}
+	if path == "" {
+		path = DefaultLibrary
+	}
+	if loadedPath != "" {
+		if path != loadedPath {
+			return fmt.Errorf("ocgcore: %s already loaded, can't load %s", loadedPath, path)
+		}
+		return nil
+	}
+
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if err := C.ocgcore_load(cpath); err != nil {
+		return fmt.Errorf("ocgcore: loading %s: %s", path, C.GoString(err))
+	}
+
+	major, minor := Version()
+	bindingMajor, bindingMinor := BindingVersion()
+	if major != bindingMajor || minor < bindingMinor {
+		C.ocgcore_unload()
+		return fmt.Errorf("ocgcore: %s has API version %d.%d, the bindings need %d.%d", path, major, minor, bindingMajor, bindingMinor)
+	}
+	loadedPath = path
+	return nil
+}
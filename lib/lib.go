@@ -1,7 +1,6 @@
 package lib
 
 /*
-#cgo LDFLAGS: ${SRCDIR}/libocgcore.a -lstdc++
 #include "ocgapi.h"
 #include <stdlib.h>
 extern void goCallbackOCGDataReader(void* payload, uint32_t code, OCG_CardData* data);
@@ -12,70 +11,125 @@ extern void goCallbackOCGLogHandler(void* payload, char* string, int type);
 import "C"
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
-var duelLock sync.Mutex
-var lastDuelId = uintptr(0)
-var mapDuels = map[C.OCG_Duel]uintptr{}
-var mapCallbackDataReader = map[uintptr]func(code C.uint32_t, data *C.OCG_CardData){}
-var mapCallbackDataReaderDone = map[uintptr]func(data *C.OCG_CardData){}
-var mapCallbackScriptReader = map[uintptr]func(duel C.OCG_Duel, name *C.char) C.int{}
-var mapCallbackLogHandler = map[uintptr]func(str *C.char, typ C.int){}
+// duelCallbacks are the callbacks of a duel. The payload given to the core is
+// the id of the duel, which the exported callbacks look up in the registry.
+type duelCallbacks struct {
+	dataReader     func(code C.uint32_t, data *C.OCG_CardData)
+	dataReaderDone func(data *C.OCG_CardData)
+	scriptReader   func(duel C.OCG_Duel, name *C.char) C.int
+	logHandler     func(str *C.char, typ C.int)
+}
+
+// The registry is written once when a duel is created and once when it's
+// destroyed, and read on every callback. Duels don't share any lock.
+var lastDuelId uintptr
+var activeDuels int64
+var mapDuels sync.Map     // C.OCG_Duel -> uintptr
+var mapCallbacks sync.Map // uintptr -> *duelCallbacks
+
+func lookupCallbacks(p unsafe.Pointer) *duelCallbacks {
+	cb, ok := mapCallbacks.Load(uintptr(p))
+	if !ok {
+		return nil
+	}
+	return cb.(*duelCallbacks)
+}
+
+func registerCallbacks(cb *duelCallbacks) uintptr {
+	id := atomic.AddUintptr(&lastDuelId, 1)
+	mapCallbacks.Store(id, cb)
+	atomic.AddInt64(&activeDuels, 1)
+	return id
+}
+
+func cleanupCallbacks(id uintptr) {
+	mapCallbacks.Delete(id)
+	atomic.AddInt64(&activeDuels, -1)
+}
+
+// ActiveDuels returns the number of duels that have been created and not yet
+// destroyed, including the ones being created.
+func ActiveDuels() int {
+	return int(atomic.LoadInt64(&activeDuels))
+}
 
 //export goCallbackOCGDataReader
 func goCallbackOCGDataReader(p unsafe.Pointer, code C.uint32_t, data *C.OCG_CardData) {
-	fn := mapCallbackDataReader[uintptr(p)]
-	fn(code, data)
+	if cb := lookupCallbacks(p); cb != nil {
+		cb.dataReader(code, data)
+	}
 }
 
 //export goCallbackOCGDataReaderDone
 func goCallbackOCGDataReaderDone(p unsafe.Pointer, data *C.OCG_CardData) {
-	fn := mapCallbackDataReaderDone[uintptr(p)]
-	fn(data)
+	if cb := lookupCallbacks(p); cb != nil {
+		cb.dataReaderDone(data)
+	}
 }
 
 //export goCallbackOCGScriptReader
 func goCallbackOCGScriptReader(p unsafe.Pointer, duel C.OCG_Duel, name *C.char) C.int {
-	fn := mapCallbackScriptReader[uintptr(p)]
-	return fn(duel, name)
+	if cb := lookupCallbacks(p); cb != nil {
+		return cb.scriptReader(duel, name)
+	}
+	return 0
 }
 
 //export goCallbackOCGLogHandler
 func goCallbackOCGLogHandler(p unsafe.Pointer, str *C.char, typ C.int) {
-	fn := mapCallbackLogHandler[uintptr(p)]
-	fn(str, typ)
+	if cb := lookupCallbacks(p); cb != nil {
+		cb.logHandler(str, typ)
+	}
 }
 
-func CreateDuel(options DuelOptions) Duel {
-	duelLock.Lock()
-	defer duelLock.Unlock()
+// Version returns the API version of the core. With a shared library core,
+// it must be loaded first.
+func Version() (major int, minor int) {
+	var cmajor, cminor C.int
+	C.OCG_GetVersion(&cmajor, &cminor)
+	return int(cmajor), int(cminor)
+}
 
+// BindingVersion returns the API version of the header the bindings were
+// built with.
+func BindingVersion() (major int, minor int) {
+	return C.OCG_VERSION_MAJOR, C.OCG_VERSION_MINOR
+}
+
+func CreateDuel(options DuelOptions) (Duel, error) {
 	var cduel C.OCG_Duel
-	lastDuelId++
 
 	if options.CardReader == nil {
-		panic("card reader nil")
+		return 0, errors.New("card reader nil")
 	}
 	if options.ScriptReader == nil {
-		panic("script reader nil")
+		return 0, errors.New("script reader nil")
 	}
 
-	mapCallbackDataReader[lastDuelId] = func(code C.uint32_t, data *C.OCG_CardData) {
+	cb := &duelCallbacks{}
+	cb.dataReader = func(code C.uint32_t, data *C.OCG_CardData) {
 		d := options.CardReader(uint32(code))
-		setcodes := make([]C.uint16_t, len(d.SetCodes)+1)
+
+		// the core keeps the setcodes until it calls the done callback, so
+		// they can't live in Go memory
+		setcodes := (*[1 << 16]C.uint16_t)(C.malloc(C.size_t(len(d.SetCodes)+1) * C.size_t(unsafe.Sizeof(C.uint16_t(0)))))
 		for i, sc := range d.SetCodes {
 			setcodes[i] = C.uint16_t(sc)
 		}
 		setcodes[len(d.SetCodes)] = 0
 
 		*data = C.OCG_CardData{
-			code:     C.uint32_t(d.Code),
-			alias:    C.uint32_t(d.Alias),
-			setcodes: (*C.uint16_t)(&setcodes[0]),
-			//type_:       C.uint32_t(d.Type),
+			code:        C.uint32_t(d.Code),
+			alias:       C.uint32_t(d.Alias),
+			setcodes:    &setcodes[0],
+			_type:       C.uint32_t(d.Type),
 			level:       C.uint32_t(d.Level),
 			attribute:   C.uint32_t(d.Attribute),
 			race:        C.uint32_t(d.Race),
@@ -86,20 +140,29 @@ func CreateDuel(options DuelOptions) Duel {
 			link_marker: C.uint32_t(d.LinkMarker),
 		}
 	}
-	mapCallbackScriptReader[lastDuelId] = func(duel C.OCG_Duel, name *C.char) C.int {
+	cb.scriptReader = func(duel C.OCG_Duel, name *C.char) C.int {
 		ok := options.ScriptReader(Duel(duel), C.GoString(name))
 		if ok {
 			return 1
 		}
 		return 0
 	}
-	mapCallbackLogHandler[lastDuelId] = func(str *C.char, typ C.int) {
-		options.LogHandler(C.GoString(str), int(typ))
+	cb.logHandler = func(str *C.char, typ C.int) {
+		options.LogHandler(C.GoString(str), LogType(typ))
 	}
-	mapCallbackDataReaderDone[lastDuelId] = func(data *C.OCG_CardData) {
-		// TODO: implement
+	cb.dataReaderDone = func(data *C.OCG_CardData) {
+		if options.CardReaderDone != nil {
+			options.CardReaderDone(CardData{
+				Code:     uint32(data.code),
+				Alias:    uint32(data.alias),
+				SetCodes: goSetCodes(data.setcodes),
+			})
+		}
+		C.free(unsafe.Pointer(data.setcodes))
+		data.setcodes = nil
 	}
 
+	id := registerCallbacks(cb)
 	status := C.OCG_CreateDuel(&cduel, C.OCG_DuelOptions{
 		seed:  C.uint32_t(options.Seed),
 		flags: C.uint32_t(options.Flags),
@@ -114,35 +177,44 @@ func CreateDuel(options DuelOptions) Duel {
 			drawCountPerTurn:  C.uint32_t(options.Team2.DrawCountPerTurn),
 		},
 		cardReader:     C.OCG_DataReader(C.goCallbackOCGDataReader),
-		payload1:       unsafe.Pointer(lastDuelId),
+		payload1:       unsafe.Pointer(id),
 		scriptReader:   C.OCG_ScriptReader(C.goCallbackOCGScriptReader),
-		payload2:       unsafe.Pointer(lastDuelId),
+		payload2:       unsafe.Pointer(id),
 		logHandler:     C.OCG_LogHandler(C.goCallbackOCGLogHandler),
-		payload3:       unsafe.Pointer(lastDuelId),
+		payload3:       unsafe.Pointer(id),
 		cardReaderDone: C.OCG_DataReaderDone(C.goCallbackOCGDataReaderDone),
-		payload4:       unsafe.Pointer(lastDuelId),
+		payload4:       unsafe.Pointer(id),
 	})
 	if status != 0 {
-		cleanupCallbacks(lastDuelId)
-		panic(fmt.Sprintf("invalid creation status: %v", status))
+		cleanupCallbacks(id)
+		return 0, fmt.Errorf("invalid creation status: %v", status)
 	}
 
-	mapDuels[cduel] = lastDuelId
-	return Duel(cduel)
+	mapDuels.Store(cduel, id)
+	return Duel(cduel), nil
 }
 
-func cleanupCallbacks(id uintptr) {
-	delete(mapCallbackDataReader, id)
-	delete(mapCallbackScriptReader, id)
-	delete(mapCallbackLogHandler, id)
-	delete(mapCallbackDataReaderDone, id)
+// goSetCodes copies a zero terminated list of setcodes.
+func goSetCodes(p *C.uint16_t) []uint16 {
+	if p == nil {
+		return nil
+	}
+	sc := (*[1 << 16]C.uint16_t)(unsafe.Pointer(p))
+	var setCodes []uint16
+	for i := 0; sc[i] != 0; i++ {
+		setCodes = append(setCodes, uint16(sc[i]))
+	}
+	return setCodes
 }
 
 func DestroyDuel(duel Duel) {
-	duelLock.Lock()
-	defer duelLock.Unlock()
 	cduel := C.OCG_Duel(duel)
 	C.OCG_DestroyDuel(cduel)
+
+	// the core may call back while destroying the duel
+	if id, ok := mapDuels.LoadAndDelete(cduel); ok {
+		cleanupCallbacks(id.(uintptr))
+	}
 }
 
 func DuelNewCard(duel Duel, info NewCardInfo) {
@@ -177,6 +249,9 @@ func DuelSetResponse(duel Duel, buffer []byte) {
 }
 
 func LoadScript(duel Duel, buffer []byte, name string) int {
+	if len(buffer) == 0 {
+		return 0
+	}
 	cname := C.CString(name)
 	ret := C.OCG_LoadScript(C.OCG_Duel(duel), (*C.char)(unsafe.Pointer(&buffer[0])), C.uint32_t(len(buffer)), cname)
 	C.free(unsafe.Pointer(cname))
@@ -0,0 +1,58 @@
+//go:build cgo
+// +build cgo
+
+package lib
+
+import (
+	"sync"
+	"testing"
+)
+
+func countEntries(m *sync.Map) int {
+	n := 0
+	m.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestCreateDestroyDuelLeak(t *testing.T) {
+	if err := Load(""); err != nil {
+		t.Skipf("core unavailable: %v", err)
+	}
+
+	const cycles = 10000
+
+	options := DuelOptions{
+		Flags: DuelModeMR5,
+		Team1: Player{StartingLP: 8000, StartingDrawCount: 5, DrawCountPerTurn: 1},
+		Team2: Player{StartingLP: 8000, StartingDrawCount: 5, DrawCountPerTurn: 1},
+		CardReader: func(code uint32) CardData {
+			return CardData{Code: code, SetCodes: []uint16{0x1, 0x2}}
+		},
+		ScriptReader: func(duel Duel, name string) bool {
+			return false
+		},
+		LogHandler:     func(s string, typ LogType) {},
+		CardReaderDone: func(data CardData) {},
+	}
+	for i := 0; i < cycles; i++ {
+		duel, err := CreateDuel(options)
+		if err != nil {
+			t.Fatalf("cycle %d: %v", i, err)
+		}
+		DuelNewCard(duel, NewCardInfo{Code: 89631139, Location: LocationDeck, Position: PositionFaceDownDefense})
+		DestroyDuel(duel)
+	}
+
+	if n := countEntries(&mapDuels); n != 0 {
+		t.Errorf("%d entries left in mapDuels after %d cycles", n, cycles)
+	}
+	if n := countEntries(&mapCallbacks); n != 0 {
+		t.Errorf("%d entries left in mapCallbacks after %d cycles", n, cycles)
+	}
+	if n := ActiveDuels(); n != 0 {
+		t.Errorf("%d active duels left after %d cycles", n, cycles)
+	}
+}
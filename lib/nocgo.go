@@ -0,0 +1,11 @@
+//go:build !cgo
+// +build !cgo
+
+package lib
+
+import "errors"
+
+// Load fails without cgo, the core can't be called.
+func Load(path string) error {
+	return errors.New("ocgcore: built without cgo, the core is unavailable")
+}
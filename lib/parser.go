@@ -1,70 +1,74 @@
 package lib
 
 import (
-	"bytes"
-	"encoding/binary"
-	"io"
+	"fmt"
 	"ocgcore/utils"
 )
 
-func ParseQuery(data []byte) ParsedQueryResult {
-	b := bytes.NewBuffer(data)
+// The parsed queries share the memory of data.
 
-	res := ParsedQueryResult{}
-	for {
-		var length uint16
-		err := binary.Read(b, binary.LittleEndian, &length)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			panic(err)
-		}
-		query := utils.ReadInt32(b)
-		queryData := make([]byte, length-4)
-		querySize, err := b.Read(queryData)
+// readQuery reads a query of a card after its length, which includes the
+// query type.
+func readQuery(b *utils.Reader, length int) (Query, []byte, error) {
+	if err := b.Err(); err != nil {
+		return 0, nil, err
+	}
+	if length < 4 {
+		return 0, nil, fmt.Errorf("query at offset %d: length %d is too short", b.Offset()-2, length)
+	}
+	query := Query(b.Field("query").Uint32())
+	data := b.Field("data").Bytes(length - 4)
+	return query, data, b.Err()
+}
+
+func ParseQuery(data []byte) (ParsedQueryResult, error) {
+	b := utils.NewReader(data)
 
-		if err != nil || querySize != int(length-4) {
-			panic(err)
+	res := ParsedQueryResult{}
+	for b.Len() > 0 {
+		query, queryData, err := readQuery(b, int(b.Field("length").Uint16()))
+		if err != nil {
+			return nil, err
 		}
-		res[Query(query)] = queryData
+		res[query] = queryData
 	}
-	return res
+	return res, nil
 }
 
-func ParseQueryLocation(data []byte) []ParsedQueryResult {
-	b := bytes.NewBuffer(data)
+func ParseQueryLocation(data []byte) ([]ParsedQueryResult, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	b := utils.NewReader(data)
 
 	var res []ParsedQueryResult
-	size := utils.ReadUint32(b)
+	size := b.Field("size").Uint32()
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
 	if size == 0 {
-		return nil
+		return nil, nil
 	}
 
 	for b.Len() > 0 {
 		cardRes := ParsedQueryResult{}
-		for {
-			var length uint16
-			err := binary.Read(b, binary.LittleEndian, &length)
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				panic(err)
+		for b.Len() > 0 {
+			length := int(b.Field("length").Uint16())
+			if err := b.Err(); err != nil {
+				return nil, err
 			}
 			if length == 0 {
 				break
 			}
 
-			query := utils.ReadUint32(b)
-			queryData := make([]byte, length-4)
-			querySize, err := b.Read(queryData)
-
-			if err != nil || querySize != int(length-4) {
-				panic(err)
+			query, queryData, err := readQuery(b, length)
+			if err != nil {
+				return nil, err
 			}
-			if query == uint32(QueryEnd) {
+			if query == QueryEnd {
 				break
 			}
-			cardRes[Query(query)] = queryData
+			cardRes[query] = queryData
 		}
 		if len(cardRes) > 0 {
 			res = append(res, cardRes)
@@ -72,55 +76,58 @@ func ParseQueryLocation(data []byte) []ParsedQueryResult {
 			res = append(res, nil)
 		}
 	}
-	return res
+	return res, nil
 }
 
-func ParseQueryField(data []byte) ParsedQueryField {
-	b := bytes.NewBuffer(data)
+func ParseQueryField(data []byte) (ParsedQueryField, error) {
+	b := utils.NewReader(data)
 
 	var field ParsedQueryField
-	field.duelOptions = utils.ReadInt32(b)
+	field.duelOptions = b.Int32()
 	parsePlayer(b, &field.player1)
 	parsePlayer(b, &field.player2)
-	chainSize := utils.ReadInt32(b)
-	for i := 0; i < int(chainSize); i++ {
+	chainSize := b.Field("chainSize").Count32()
+	for i := 0; i < chainSize; i++ {
 		field.chain = append(field.chain, ParsedQueryFieldChain{
-			code:                 utils.ReadInt32(b),
-			controller:           utils.ReadUint8(b),
-			location:             utils.ReadUint8(b),
-			sequence:             utils.ReadUint32(b),
-			position:             utils.ReadUint32(b),
-			triggeringController: utils.ReadUint8(b),
-			triggeringLocation:   utils.ReadUint8(b),
-			triggeringSequence:   utils.ReadUint32(b),
-			description:          utils.ReadUint64(b),
+			code:                 b.Int32(),
+			controller:           b.Uint8(),
+			location:             b.Uint8(),
+			sequence:             b.Uint32(),
+			position:             b.Uint32(),
+			triggeringController: b.Uint8(),
+			triggeringLocation:   b.Uint8(),
+			triggeringSequence:   b.Uint32(),
+			description:          b.Uint64(),
 		})
 	}
-	return field
+	if err := b.Err(); err != nil {
+		return ParsedQueryField{}, err
+	}
+	return field, nil
 }
 
-func parsePlayer(b *bytes.Buffer, player *ParsedQueryFieldPlayer) {
-	player.lp = utils.ReadInt32(b)
+func parsePlayer(b *utils.Reader, player *ParsedQueryFieldPlayer) {
+	player.lp = b.Int32()
 	for i := 0; i < 7; i++ {
-		player.monsters[i].present = utils.ReadUint8(b) != 0
+		player.monsters[i].present = b.Uint8() != 0
 		if player.monsters[i].present {
-			player.monsters[i].position = utils.ReadInt8(b)
-			player.monsters[i].materials = utils.ReadInt32(b)
+			player.monsters[i].position = b.Int8()
+			player.monsters[i].materials = b.Int32()
 		}
 	}
 	for i := 0; i < 8; i++ {
-		player.spells[i].present = utils.ReadUint8(b) != 0
+		player.spells[i].present = b.Uint8() != 0
 		if player.spells[i].present {
-			player.spells[i].position = utils.ReadInt8(b)
-			player.spells[i].materials = utils.ReadInt32(b)
+			player.spells[i].position = b.Int8()
+			player.spells[i].materials = b.Int32()
 		}
 	}
-	player.mainCount = utils.ReadUint32(b)
-	player.handCount = utils.ReadUint32(b)
-	player.graveCount = utils.ReadUint32(b)
-	player.banishCount = utils.ReadUint32(b)
-	player.extraCount = utils.ReadUint32(b)
-	player.extraPCount = utils.ReadUint32(b)
+	player.mainCount = b.Uint32()
+	player.handCount = b.Uint32()
+	player.graveCount = b.Uint32()
+	player.banishCount = b.Uint32()
+	player.extraCount = b.Uint32()
+	player.extraPCount = b.Uint32()
 }
 
 type ParsedQueryResult map[Query][]byte
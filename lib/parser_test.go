@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func encode(fields ...interface{}) []byte {
+	var b bytes.Buffer
+	for _, f := range fields {
+		if err := binary.Write(&b, binary.LittleEndian, f); err != nil {
+			panic(err)
+		}
+	}
+	return b.Bytes()
+}
+
+func TestParseQuery(t *testing.T) {
+	data := encode(uint16(8), uint32(QueryCode), uint32(89631139), uint16(4), uint32(QueryEnd))
+	res, err := ParseQuery(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := binary.LittleEndian.Uint32(res[QueryCode]); code != 89631139 {
+		t.Fatalf("got code %d", code)
+	}
+
+	for _, data := range [][]byte{
+		encode(uint16(2), uint32(QueryCode)),
+		encode(uint16(12), uint32(QueryCode), uint32(89631139)),
+		{8},
+	} {
+		if _, err := ParseQuery(data); err == nil {
+			t.Errorf("%x: no error", data)
+		}
+	}
+}
+
+func TestParseQueryLocation(t *testing.T) {
+	card := encode(uint16(8), uint32(QueryCode), uint32(89631139), uint16(4), uint32(QueryEnd))
+	cards := append(append(append([]byte{}, card...), 0, 0), card...)
+	res, err := ParseQueryLocation(append(encode(uint32(len(cards))), cards...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 || res[0] == nil || res[1] != nil || res[2] == nil {
+		t.Fatalf("got %v", res)
+	}
+
+	// a truncated length used to loop forever
+	if _, err := ParseQueryLocation(encode(uint32(1), uint8(1))); err == nil {
+		t.Error("no error")
+	}
+}
+
+func TestParseQueryFieldCount(t *testing.T) {
+	player := encode(int32(8000), make([]byte, 15), make([]uint32, 6))
+	data := encode(int32(0), player, player, int32(-1))
+	if _, err := ParseQueryField(data); err == nil {
+		t.Fatal("no error for a negative chain count")
+	}
+}
+
+// The seeds in testdata/fuzz are built by hand, more are captured from real
+// duels with cmd/bench -corpus.
+
+func FuzzParseQuery(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ParseQuery(data)
+	})
+}
+
+func FuzzParseQueryLocation(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ParseQueryLocation(data)
+	})
+}
+
+func FuzzParseQueryField(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ParseQueryField(data)
+	})
+}
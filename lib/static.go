@@ -0,0 +1,18 @@
+//go:build !ocgcore_dynamic
+// +build !ocgcore_dynamic
+
+package lib
+
+// #cgo LDFLAGS: ${SRCDIR}/libocgcore.a -lstdc++
+import "C"
+
+import "errors"
+
+// Load does nothing when the core is linked statically, and fails when asked
+// for a shared library.
+func Load(path string) error {
+	if path != "" {
+		return errors.New("ocgcore: the core is linked statically, build with -tags ocgcore_dynamic to load " + path)
+	}
+	return nil
+}
@@ -9,7 +9,7 @@ type DuelOptions struct {
 	Team2          Player
 	CardReader     func(code uint32) CardData
 	ScriptReader   func(duel Duel, name string) bool
-	LogHandler     func(s string, typ int)
+	LogHandler     func(s string, typ LogType)
 	CardReaderDone func(data CardData)
 }
 
@@ -0,0 +1,118 @@
+package ocgcore
+
+import (
+	"fmt"
+	"log"
+	"ocgcore/lib"
+	"time"
+)
+
+type LogType int
+
+const (
+	LogTypeError LogType = iota
+	LogTypeFromScript
+	LogTypeForDebug
+	LogTypeUndefined
+)
+
+var logTypeNames = []string{"error", "from_script", "for_debug", "undefined"}
+
+func (t LogType) String() string {
+	if t < 0 || int(t) >= len(logTypeNames) {
+		return fmt.Sprintf("LogType(%d)", int(t))
+	}
+	return logTypeNames[t]
+}
+
+func (t LogType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *LogType) UnmarshalText(b []byte) error {
+	for i, name := range logTypeNames {
+		if name == string(b) {
+			*t = LogType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not belong to LogType values", b)
+}
+
+func parseLogType(typ lib.LogType) LogType {
+	switch typ {
+	case lib.LogTypeError:
+		return LogTypeError
+	case lib.LogTypeFromScript:
+		return LogTypeFromScript
+	case lib.LogTypeForDebug:
+		return LogTypeForDebug
+	default:
+		return LogTypeUndefined
+	}
+}
+
+// LogEntry is a message logged by the core, or by the binding about the
+// scripts it couldn't find.
+type LogEntry struct {
+	DuelID  uint64    `json:"duel_id"`
+	Type    LogType   `json:"type"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+type LogHandler func(entry LogEntry)
+
+// DefaultLogHandler writes the entries to the standard logger.
+func DefaultLogHandler(entry LogEntry) {
+	log.Printf("duel %d: %s: %s", entry.DuelID, entry.Type, entry.Message)
+}
+
+// KeyValueLogger is satisfied by *slog.Logger.
+type KeyValueLogger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// KeyValueLogHandler logs errors as errors, script messages as info, debug
+// messages as debug and anything else as a warning. The duel id and the log
+// type are added as attributes.
+func KeyValueLogHandler(l KeyValueLogger) LogHandler {
+	return func(entry LogEntry) {
+		args := []interface{}{"duel", entry.DuelID, "type", entry.Type.String()}
+		switch entry.Type {
+		case LogTypeError:
+			l.Error(entry.Message, args...)
+		case LogTypeFromScript:
+			l.Info(entry.Message, args...)
+		case LogTypeForDebug:
+			l.Debug(entry.Message, args...)
+		default:
+			l.Warn(entry.Message, args...)
+		}
+	}
+}
+
+// SugaredLogger is satisfied by *zap.SugaredLogger.
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// SugaredLogHandler is the same as KeyValueLogHandler for zap.
+func SugaredLogHandler(l SugaredLogger) LogHandler {
+	return KeyValueLogHandler(sugaredLogger{l})
+}
+
+type sugaredLogger struct {
+	l SugaredLogger
+}
+
+func (s sugaredLogger) Debug(msg string, args ...interface{}) { s.l.Debugw(msg, args...) }
+func (s sugaredLogger) Info(msg string, args ...interface{})  { s.l.Infow(msg, args...) }
+func (s sugaredLogger) Warn(msg string, args ...interface{})  { s.l.Warnw(msg, args...) }
+func (s sugaredLogger) Error(msg string, args ...interface{}) { s.l.Errorw(msg, args...) }
@@ -0,0 +1,403 @@
+package ocgcore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Deck is the deck registered by a player for a match. Cards can be swapped
+// between the main or extra deck and the side deck between games.
+type Deck struct {
+	Main  []uint32 `json:"main"`
+	Extra []uint32 `json:"extra"`
+	Side  []uint32 `json:"side"`
+}
+
+func (d Deck) copy() Deck {
+	return Deck{
+		Main:  append([]uint32(nil), d.Main...),
+		Extra: append([]uint32(nil), d.Extra...),
+		Side:  append([]uint32(nil), d.Side...),
+	}
+}
+
+func (d Deck) cards() []uint32 {
+	cards := make([]uint32, 0, len(d.Main)+len(d.Extra)+len(d.Side))
+	cards = append(cards, d.Main...)
+	cards = append(cards, d.Extra...)
+	cards = append(cards, d.Side...)
+	sort.Slice(cards, func(i, j int) bool { return cards[i] < cards[j] })
+	return cards
+}
+
+type MatchOptions struct {
+	Duel   CreateDuelOptions
+	BestOf int
+	Decks  [2]Deck
+
+	// Chooser is the player choosing whether to go first in the first game,
+	// usually the winner of a coin toss.
+	Chooser int
+}
+
+// GameResult is the outcome of a single game of a match. Players are match
+// players, not duel teams, and Winner is 2 for a draw.
+type GameResult struct {
+	First  int `json:"first"`
+	Winner int `json:"winner"`
+	Reason int `json:"reason"`
+}
+
+type MatchResult struct {
+	Wins   [2]int       `json:"wins"`
+	Games  []GameResult `json:"games"`
+	Winner int          `json:"winner"`
+}
+
+type MatchEvent interface {
+	matchEvent()
+}
+
+// MatchEventChooseFirst asks a player to choose whether to go first in the
+// next game.
+type MatchEventChooseFirst struct {
+	Player int `json:"player"`
+}
+
+type MatchEventGameStart struct {
+	Game  int `json:"game"`
+	First int `json:"first"`
+}
+
+type MatchEventGameEnd struct {
+	Game   int        `json:"game"`
+	Result GameResult `json:"result"`
+	Wins   [2]int     `json:"wins"`
+}
+
+type MatchEventEnd struct {
+	Result MatchResult `json:"result"`
+}
+
+func (MatchEventChooseFirst) matchEvent() {}
+func (MatchEventGameStart) matchEvent()   {}
+func (MatchEventGameEnd) matchEvent()     {}
+func (MatchEventEnd) matchEvent()         {}
+
+// Match plays successive games between two players until one of them wins
+// the majority of a best-of-N. Since the core always gives the first turn to
+// team 0, the player going first is seated as team 0 in every game.
+type Match struct {
+	options  MatchOptions
+	original [2]Deck
+	decks    [2]Deck
+
+	lock    sync.Mutex
+	duel    *OcgDuel
+	first   int
+	chooser int
+	chosen  bool
+	result  MatchResult
+	done    chan struct{}
+
+	// the events are queued under the lock and sent by deliver, so that a
+	// slow reader never blocks the match
+	events  chan MatchEvent
+	queue   []MatchEvent
+	ended   bool
+	pending chan struct{}
+}
+
+func NewMatch(options MatchOptions) (*Match, error) {
+	if options.BestOf == 0 {
+		options.BestOf = 3
+	}
+	if options.BestOf < 0 || options.BestOf%2 == 0 {
+		return nil, errors.New("best of must be an odd number of games")
+	}
+	if options.Chooser != 0 && options.Chooser != 1 {
+		return nil, errors.New("invalid chooser")
+	}
+
+	m := &Match{
+		options: options,
+		chooser: options.Chooser,
+		result:  MatchResult{Winner: -1},
+		done:    make(chan struct{}),
+		events:  make(chan MatchEvent),
+		pending: make(chan struct{}, 1),
+	}
+	for p, deck := range options.Decks {
+		m.original[p] = deck.copy()
+		m.decks[p] = deck.copy()
+	}
+	m.emit(MatchEventChooseFirst{Player: m.chooser})
+	go m.deliver()
+	return m, nil
+}
+
+// emit queues an event, the lock must be held except before deliver starts.
+func (m *Match) emit(e MatchEvent) {
+	m.queue = append(m.queue, e)
+	select {
+	case m.pending <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued events until the match ends or is destroyed, then
+// closes the events channel.
+func (m *Match) deliver() {
+	defer close(m.events)
+	for {
+		m.lock.Lock()
+		queue, ended := m.queue, m.ended
+		m.queue = nil
+		m.lock.Unlock()
+
+		if len(queue) == 0 {
+			if ended {
+				return
+			}
+			select {
+			case <-m.pending:
+			case <-m.done:
+				return
+			}
+			continue
+		}
+		for _, e := range queue {
+			select {
+			case m.events <- e:
+			case <-m.done:
+				return
+			}
+		}
+	}
+}
+
+// Events returns the match events channel, it is closed once the match ends
+// or is destroyed.
+func (m *Match) Events() <-chan MatchEvent {
+	return m.events
+}
+
+func (m *Match) Result() MatchResult {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := m.result
+	result.Games = append([]GameResult(nil), m.result.Games...)
+	return result
+}
+
+// Chooser returns the player that chooses who goes first in the next game.
+func (m *Match) Chooser() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.chooser
+}
+
+// Team returns the duel team of a player in the current game.
+func (m *Match) Team(player int) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return player ^ m.first
+}
+
+// Player returns the player seated as a duel team in the current game.
+func (m *Match) Player(team int) int {
+	return m.Team(team)
+}
+
+func (m *Match) ChooseFirst(player int, goFirst bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.checkBetweenGames(); err != nil {
+		return err
+	}
+	if player != m.chooser {
+		return errors.New("not your choice")
+	}
+	m.first = player
+	if !goFirst {
+		m.first = 1 - player
+	}
+	m.chosen = true
+	return nil
+}
+
+// SideDeck replaces the deck of a player for the next games. The new deck
+// must contain exactly the cards of the registered one, with the main, extra
+// and side deck sizes unchanged.
+func (m *Match) SideDeck(player int, deck Deck) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.checkBetweenGames(); err != nil {
+		return err
+	}
+	if player != 0 && player != 1 {
+		return errors.New("invalid player")
+	}
+	if len(m.result.Games) == 0 {
+		return errors.New("side decking is only allowed between games")
+	}
+
+	original := m.original[player]
+	if len(deck.Main) != len(original.Main) || len(deck.Extra) != len(original.Extra) || len(deck.Side) != len(original.Side) {
+		return errors.New("deck sizes must not change")
+	}
+	a, b := original.cards(), deck.cards()
+	for i := range a {
+		if a[i] != b[i] {
+			return fmt.Errorf("card %d is not part of the registered deck", b[i])
+		}
+	}
+	m.decks[player] = deck.copy()
+	return nil
+}
+
+func (m *Match) checkBetweenGames() error {
+	select {
+	case <-m.done:
+		return errors.New("match destroyed")
+	default:
+	}
+	if m.result.Winner >= 0 {
+		return errors.New("match already ended")
+	}
+	if m.duel != nil {
+		return errors.New("game in progress")
+	}
+	return nil
+}
+
+// StartGame starts the next game once the chooser made their choice. The
+// returned channel is closed when the game ends, after the result has been
+// recorded.
+func (m *Match) StartGame() (*OcgDuel, <-chan Message, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.checkBetweenGames(); err != nil {
+		return nil, nil, err
+	}
+	if !m.chosen {
+		return nil, nil, errors.New("waiting for the first player choice")
+	}
+
+	duel, err := CreateDuel(m.options.Duel)
+	if err != nil {
+		return nil, nil, err
+	}
+	for p, deck := range m.decks {
+		// copy the deck, shuffling is done in place
+		d := deck.copy()
+		duel.SetupDeck(p^m.first, d.Main, d.Extra, true)
+	}
+	m.duel = duel
+	m.chosen = false
+
+	game := len(m.result.Games) + 1
+	m.emit(MatchEventGameStart{Game: game, First: m.first})
+
+	out := make(chan Message)
+	go m.forward(duel, duel.Start(), out)
+	return duel, out, nil
+}
+
+func (m *Match) forward(duel *OcgDuel, in <-chan Message, out chan<- Message) {
+	defer close(out)
+
+	var win *MessageWin
+	for msg := range in {
+		if w, ok := msg.(MessageWin); ok {
+			win = &w
+		}
+		select {
+		case out <- msg:
+		case <-m.done:
+			return
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.duel != duel {
+		return
+	}
+	m.duel = nil
+	duel.Destroy()
+
+	if win == nil {
+		// the game doesn't count, the same player chooses again
+		m.emit(MatchEventChooseFirst{Player: m.chooser})
+		return
+	}
+	m.endGame(*win)
+}
+
+func (m *Match) endGame(win MessageWin) {
+	result := GameResult{First: m.first, Winner: 2, Reason: win.Reason}
+	if win.Player == 0 || win.Player == 1 {
+		result.Winner = win.Player ^ m.first
+		m.result.Wins[result.Winner]++
+		// the loser chooses, after a draw the same player chooses again
+		m.chooser = 1 - result.Winner
+	}
+	m.result.Games = append(m.result.Games, result)
+
+	m.emit(MatchEventGameEnd{
+		Game:   len(m.result.Games),
+		Result: result,
+		Wins:   m.result.Wins,
+	})
+
+	needed := m.options.BestOf/2 + 1
+	switch {
+	case m.result.Wins[0] >= needed:
+		m.result.Winner = 0
+	case m.result.Wins[1] >= needed:
+		m.result.Winner = 1
+	case len(m.result.Games) >= m.options.BestOf:
+		switch {
+		case m.result.Wins[0] > m.result.Wins[1]:
+			m.result.Winner = 0
+		case m.result.Wins[1] > m.result.Wins[0]:
+			m.result.Winner = 1
+		default:
+			m.result.Winner = 2
+		}
+	}
+
+	if m.result.Winner >= 0 {
+		result := m.result
+		result.Games = append([]GameResult(nil), m.result.Games...)
+		m.emit(MatchEventEnd{Result: result})
+		m.ended = true
+		return
+	}
+	m.emit(MatchEventChooseFirst{Player: m.chooser})
+}
+
+// Destroy stops the current game, if any. The match can't be resumed.
+func (m *Match) Destroy() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	select {
+	case <-m.done:
+		return
+	default:
+	}
+	close(m.done)
+	if m.duel != nil {
+		m.duel.Destroy()
+		m.duel = nil
+	}
+}
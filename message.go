@@ -1,7 +1,7 @@
 package ocgcore
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"ocgcore/lib"
 	"ocgcore/utils"
@@ -17,66 +17,101 @@ type cardLocation struct {
 	position   lib.Position
 }
 
-func readMessage(contents []byte) Message {
-	b := bytes.NewBuffer(contents)
+// ErrNotImplemented is returned for the messages the core can send but that
+// aren't decoded yet.
+var ErrNotImplemented = errors.New("decoder not implemented")
 
-	id := utils.ReadUint8(b)
+// ReadMessage decodes a message produced by the core. Unknown messages and
+// messages shorter than their layout are errors, as are bytes left after a
+// message, which still returns the decoded message.
+func ReadMessage(contents []byte) (Message, error) {
+	return readMessage(contents)
+}
+
+func readMessage(contents []byte) (Message, error) {
+	b := utils.NewReader(contents)
+	msg, err := readMessageType(b)
+	if err != nil {
+		return nil, fmt.Errorf("message %s: %w", msg.messageType(), err)
+	}
+	if msg == nil {
+		if len(contents) == 0 {
+			return nil, errors.New("empty message")
+		}
+		return nil, fmt.Errorf("unhandled message %d, size %d", contents[0], len(contents)-1)
+	}
+
+	var e *utils.ReadError
+	if errors.As(b.Err(), &e) {
+		if e.Field == "" {
+			return nil, fmt.Errorf("message %s truncated at offset %d", msg.messageType(), e.Offset)
+		}
+		return nil, fmt.Errorf("message %s truncated at offset %d: expected %s", msg.messageType(), e.Offset, e.Field)
+	}
+	if b.Len() > 0 {
+		return msg, fmt.Errorf("message %s has %d trailing bytes at offset %d", msg.messageType(), b.Len(), b.Offset())
+	}
+	return msg, nil
+}
+
+func readMessageType(b *utils.Reader) (Message, error) {
+	id := b.Uint8()
 
 	switch lib.Message(id) {
 	case lib.MessageRetry:
-		return ReadMessageRetry(b)
+		return ReadMessageRetry(b), nil
 	case lib.MessageHint:
-		return ReadMessageHint(b)
+		return ReadMessageHint(b), nil
 	case lib.MessageWaiting:
-		return ReadMessageWaiting(b)
+		return ReadMessageWaiting(b), nil
 	case lib.MessageStart:
-		return ReadMessageStart(b)
+		return ReadMessageStart(b), nil
 	case lib.MessageWin:
-		return ReadMessageWin(b)
+		return ReadMessageWin(b), nil
 	case lib.MessageUpdateData:
-		return ReadMessageUpdateData(b)
+		return ReadMessageUpdateData(b), nil
 	case lib.MessageUpdateCard:
-		return ReadMessageUpdateCard(b)
+		return ReadMessageUpdateCard(b), nil
 	case lib.MessageRequestDeck:
-		return ReadMessageRequestDeck(b)
+		return ReadMessageRequestDeck(b), nil
 	case lib.MessageSelectBattleCMD:
-		return ReadMessageSelectBattleCMD(b)
+		return ReadMessageSelectBattleCMD(b), nil
 	case lib.MessageSelectIdleCMD:
-		return ReadMessageSelectIdleCMD(b)
+		return ReadMessageSelectIdleCMD(b), nil
 	case lib.MessageSelectEffectYN:
-		return ReadMessageSelectEffectYN(b)
+		return ReadMessageSelectEffectYN(b), nil
 	case lib.MessageSelectYesNo:
-		return ReadMessageSelectYesNo(b)
+		return ReadMessageSelectYesNo(b), nil
 	case lib.MessageSelectOption:
-		return ReadMessageSelectOption(b)
+		return ReadMessageSelectOption(b), nil
 	case lib.MessageSelectCard:
-		return ReadMessageSelectCard(b)
+		return ReadMessageSelectCard(b), nil
 	case lib.MessageSelectChain:
-		return ReadMessageSelectChain(b)
+		return ReadMessageSelectChain(b), nil
 	case lib.MessageSelectPlace:
-		return ReadMessageSelectPlace(b)
+		return ReadMessageSelectPlace(b), nil
 	case lib.MessageSelectPosition:
-		return ReadMessageSelectPosition(b)
+		return ReadMessageSelectPosition(b), nil
 	case lib.MessageSelectTribute:
-		return ReadMessageSelectTribute(b)
+		return ReadMessageSelectTribute(b), nil
 	case lib.MessageSortChain:
-		return ReadMessageSortChain(b)
+		return ReadMessageSortChain(b), nil
 	case lib.MessageSelectCounter:
-		return ReadMessageSelectCounter(b)
+		return ReadMessageSelectCounter(b), nil
 	case lib.MessageSelectSum:
-		return ReadMessageSelectSum(b)
+		return ReadMessageSelectSum(b), nil
 	case lib.MessageSelectDisfield:
-		return ReadMessageSelectDisfield(b)
+		return ReadMessageSelectDisfield(b), nil
 	case lib.MessageSortCard:
-		return ReadMessageSortCard(b)
+		return ReadMessageSortCard(b), nil
 	case lib.MessageSelectUnselectCard:
-		return ReadMessageSelectUnselectCard(b)
+		return ReadMessageSelectUnselectCard(b), nil
 	case lib.MessageConfirmDeckTop:
-		return ReadMessageConfirmDeckTop(b)
+		return ReadMessageConfirmDeckTop(b), nil
 	case lib.MessageConfirmCards:
-		return ReadMessageConfirmCards(b)
+		return ReadMessageConfirmCards(b), nil
 	case lib.MessageShuffleDeck:
-		return ReadMessageShuffleDeck(b)
+		return ReadMessageShuffleDeck(b), nil
 	case lib.MessageShuffleHand:
 		return ReadMessageShuffleHand(b)
 	case lib.MessageRefreshDeck:
@@ -92,13 +127,13 @@ func readMessage(contents []byte) Message {
 	case lib.MessageShuffleExtra:
 		return ReadMessageShuffleExtra(b)
 	case lib.MessageNewTurn:
-		return ReadMessageNewTurn(b)
+		return ReadMessageNewTurn(b), nil
 	case lib.MessageNewPhase:
-		return ReadMessageNewPhase(b)
+		return ReadMessageNewPhase(b), nil
 	case lib.MessageConfirmExtraTop:
 		return ReadMessageConfirmExtraTop(b)
 	case lib.MessageMove:
-		return ReadMessageMove(b)
+		return ReadMessageMove(b), nil
 	case lib.MessagePosChange:
 		return ReadMessagePosChange(b)
 	case lib.MessageSet:
@@ -108,27 +143,27 @@ func readMessage(contents []byte) Message {
 	case lib.MessageFieldDisabled:
 		return ReadMessageFieldDisabled(b)
 	case lib.MessageSummoning:
-		return ReadMessageSummoning(b)
+		return ReadMessageSummoning(b), nil
 	case lib.MessageSummoned:
-		return ReadMessageSummoned(b)
+		return ReadMessageSummoned(b), nil
 	case lib.MessageSPSummoning:
-		return ReadMessageSPSummoning(b)
+		return ReadMessageSPSummoning(b), nil
 	case lib.MessageSPSummoned:
-		return ReadMessageSPSummoned(b)
+		return ReadMessageSPSummoned(b), nil
 	case lib.MessageFlipSummoning:
 		return ReadMessageFlipSummoning(b)
 	case lib.MessageFlipSummoned:
 		return ReadMessageFlipSummoned(b)
 	case lib.MessageChaining:
-		return ReadMessageChaining(b)
+		return ReadMessageChaining(b), nil
 	case lib.MessageChained:
-		return ReadMessageChained(b)
+		return ReadMessageChained(b), nil
 	case lib.MessageChainSolving:
-		return ReadMessageChainSolving(b)
+		return ReadMessageChainSolving(b), nil
 	case lib.MessageChainSolved:
-		return ReadMessageChainSolved(b)
+		return ReadMessageChainSolved(b), nil
 	case lib.MessageChainEnd:
-		return ReadMessageChainEnd(b)
+		return ReadMessageChainEnd(b), nil
 	case lib.MessageChainNegated:
 		return ReadMessageChainNegated(b)
 	case lib.MessageChainDisabled:
@@ -138,9 +173,9 @@ func readMessage(contents []byte) Message {
 	case lib.MessageRandomSelected:
 		return ReadMessageRandomSelected(b)
 	case lib.MessageBecomeTarget:
-		return ReadMessageBecomeTarget(b)
+		return ReadMessageBecomeTarget(b), nil
 	case lib.MessageDraw:
-		return ReadMessageDraw(b)
+		return ReadMessageDraw(b), nil
 	case lib.MessageDamage:
 		return ReadMessageDamage(b)
 	case lib.MessageRecover:
@@ -198,13 +233,13 @@ func readMessage(contents []byte) Message {
 	case lib.MessageCardHint:
 		return ReadMessageCardHint(b)
 	case lib.MessageTagSwap:
-		return ReadMessageTagSwap(b)
+		return ReadMessageTagSwap(b), nil
 	case lib.MessageReloadField:
-		return ReadMessageReloadField(b)
+		return ReadMessageReloadField(b), nil
 	case lib.MessageAIName:
-		return ReadMessageAIName(b)
+		return ReadMessageAIName(b), nil
 	case lib.MessageShowHint:
-		return ReadMessageShowHint(b)
+		return ReadMessageShowHint(b), nil
 	case lib.MessagePlayerHint:
 		return ReadMessagePlayerHint(b)
 	case lib.MessageMatchKill:
@@ -214,17 +249,23 @@ func readMessage(contents []byte) Message {
 	case lib.MessageRemoveCards:
 		return ReadMessageRemoveCards(b)
 	default:
-		fmt.Println("unhandled message:", id, "size:", len(contents)-1)
-		return nil
+		return nil, nil
 	}
 }
 
-func readCardLocation(b *bytes.Buffer) cardLocation {
+func readString(b *utils.Reader) string {
+	str := b.Field("string").Bytes(int(b.Uint16()))
+	// strings are followed by a null terminator
+	_ = b.Uint8()
+	return string(str)
+}
+
+func readCardLocation(b *utils.Reader) cardLocation {
 	return cardLocation{
-		controller: int(utils.ReadUint8(b)),
-		location:   lib.Location(utils.ReadUint8(b)),
-		sequence:   int(utils.ReadUint32(b)),
-		position:   lib.Position(utils.ReadUint32(b)),
+		controller: int(b.Field("controller").Uint8()),
+		location:   lib.Location(b.Field("location").Uint8()),
+		sequence:   int(b.Field("sequence").Uint32()),
+		position:   lib.Position(b.Field("position").Uint32()),
 	}
 }
 
@@ -333,6 +374,46 @@ type Message interface {
 	messageType() MessageType
 }
 
+// ResponsePlayer returns the player that has to answer a prompt message. The
+// second return value is false for messages that don't expect a response.
+func ResponsePlayer(m Message) (int, bool) {
+	switch m := m.(type) {
+	case MessageSelectBattleCMD:
+		return m.Player, true
+	case MessageSelectIdleCMD:
+		return m.Player, true
+	case MessageSelectEffectYN:
+		return m.Player, true
+	case MessageSelectYesNo:
+		return m.Player, true
+	case MessageSelectOption:
+		return m.Player, true
+	case MessageSelectCard:
+		return m.Player, true
+	case MessageSelectChain:
+		return m.Player, true
+	case MessageSelectPlace:
+		return m.Player, true
+	case MessageSelectPosition:
+		return m.Player, true
+	case MessageSelectTribute:
+		return m.Player, true
+	case MessageSortChain:
+		return m.Player, true
+	case MessageSelectCounter:
+		return m.Player, true
+	case MessageSelectSum:
+		return m.Player, true
+	case MessageSelectDisfield:
+		return m.Player, true
+	case MessageSortCard:
+		return m.Player, true
+	case MessageSelectUnselectCard:
+		return m.Player, true
+	}
+	return 0, false
+}
+
 type ChainInfo struct {
 	Code        int      `json:"code"`
 	Controller  int      `json:"controller"`
@@ -405,7 +486,7 @@ func (MessageWaitingResponse) messageType() MessageType {
 
 type MessageRetry struct{}
 
-func ReadMessageRetry(*bytes.Buffer) (msg MessageRetry) {
+func ReadMessageRetry(*utils.Reader) (msg MessageRetry) {
 	return
 }
 
@@ -419,10 +500,10 @@ type MessageHint struct {
 	Desc   uint64 `json:"desc"`
 }
 
-func ReadMessageHint(b *bytes.Buffer) (msg MessageHint) {
-	msg.Hint = int(utils.ReadUint8(b))
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Desc = utils.ReadUint64(b)
+func ReadMessageHint(b *utils.Reader) (msg MessageHint) {
+	msg.Hint = int(b.Field("Hint").Uint8())
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Desc = b.Field("Desc").Uint64()
 	return
 }
 
@@ -432,7 +513,7 @@ func (MessageHint) messageType() MessageType {
 
 type MessageWaiting struct{}
 
-func ReadMessageWaiting(*bytes.Buffer) (msg MessageWaiting) {
+func ReadMessageWaiting(*utils.Reader) (msg MessageWaiting) {
 	return
 }
 
@@ -442,7 +523,7 @@ func (MessageWaiting) messageType() MessageType {
 
 type MessageStart struct{}
 
-func ReadMessageStart(*bytes.Buffer) (msg MessageStart) {
+func ReadMessageStart(*utils.Reader) (msg MessageStart) {
 	return
 }
 
@@ -455,9 +536,9 @@ type MessageWin struct {
 	Reason int `json:"reason"`
 }
 
-func ReadMessageWin(b *bytes.Buffer) (msg MessageWin) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Reason = int(utils.ReadUint8(b))
+func ReadMessageWin(b *utils.Reader) (msg MessageWin) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Reason = int(b.Field("Reason").Uint8())
 	return
 }
 
@@ -467,7 +548,7 @@ func (MessageWin) messageType() MessageType {
 
 type MessageUpdateData struct{}
 
-func ReadMessageUpdateData(*bytes.Buffer) (msg MessageUpdateData) {
+func ReadMessageUpdateData(*utils.Reader) (msg MessageUpdateData) {
 	return
 }
 
@@ -477,7 +558,7 @@ func (MessageUpdateData) messageType() MessageType {
 
 type MessageUpdateCard struct{}
 
-func ReadMessageUpdateCard(*bytes.Buffer) (msg MessageUpdateCard) {
+func ReadMessageUpdateCard(*utils.Reader) (msg MessageUpdateCard) {
 	return
 }
 
@@ -487,7 +568,7 @@ func (MessageUpdateCard) messageType() MessageType {
 
 type MessageRequestDeck struct{}
 
-func ReadMessageRequestDeck(*bytes.Buffer) (msg MessageRequestDeck) {
+func ReadMessageRequestDeck(*utils.Reader) (msg MessageRequestDeck) {
 	return
 }
 
@@ -503,35 +584,35 @@ type MessageSelectBattleCMD struct {
 	ToEP    bool         `json:"to_ep"`
 }
 
-func ReadMessageSelectBattleCMD(b *bytes.Buffer) (msg MessageSelectBattleCMD) {
-	msg.Player = int(utils.ReadUint8(b))
+func ReadMessageSelectBattleCMD(b *utils.Reader) (msg MessageSelectBattleCMD) {
+	msg.Player = int(b.Field("Player").Uint8())
 
-	selectChainsSize := utils.ReadUint32(b)
+	selectChainsSize := b.Field("selectChainsSize").Count32()
 	msg.Chains = make([]ChainInfo, selectChainsSize)
 	for i := range msg.Chains {
 		msg.Chains[i] = ChainInfo{
-			Code:        int(utils.ReadUint32(b)),
-			Controller:  int(utils.ReadUint8(b)),
-			Location:    parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:    int(utils.ReadUint32(b)),
-			Description: utils.ReadUint64(b),
-			ClientMode:  utils.ReadUint8(b),
+			Code:        int(b.Field("Code").Uint32()),
+			Controller:  int(b.Field("Controller").Uint8()),
+			Location:    parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:    int(b.Field("Sequence").Uint32()),
+			Description: b.Field("Description").Uint64(),
+			ClientMode:  b.Field("ClientMode").Uint8(),
 		}
 	}
 
-	attackableSize := utils.ReadUint32(b)
+	attackableSize := b.Field("attackableSize").Count32()
 	msg.Attacks = make([]AttackInfo, attackableSize)
 	for i := range msg.Attacks {
 		msg.Attacks[i] = AttackInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
-			Direct:     utils.ReadUint8(b) != 0,
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
+			Direct:     b.Field("Direct").Uint8() != 0,
 		}
 	}
-	msg.ToM2 = utils.ReadUint8(b) != 0
-	msg.ToEP = utils.ReadUint8(b) != 0
+	msg.ToM2 = b.Field("ToM2").Uint8() != 0
+	msg.ToEP = b.Field("ToEP").Uint8() != 0
 	return
 }
 
@@ -552,80 +633,80 @@ type MessageSelectIdleCMD struct {
 	Shuffle     bool        `json:"shuffle"`
 }
 
-func ReadMessageSelectIdleCMD(b *bytes.Buffer) (msg MessageSelectIdleCMD) {
-	msg.Player = int(utils.ReadUint8(b))
+func ReadMessageSelectIdleCMD(b *utils.Reader) (msg MessageSelectIdleCMD) {
+	msg.Player = int(b.Field("Player").Uint8())
 
-	summonableSize := utils.ReadUint32(b)
+	summonableSize := b.Field("summonableSize").Count32()
 	msg.Summons = make([]CardInfo, summonableSize)
 	for i := range msg.Summons {
 		msg.Summons[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 
-	spSummonableSize := utils.ReadUint32(b)
+	spSummonableSize := b.Field("spSummonableSize").Count32()
 	msg.SpSummons = make([]CardInfo, spSummonableSize)
 	for i := range msg.SpSummons {
 		msg.SpSummons[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 
-	posChangeSize := utils.ReadUint32(b)
+	posChangeSize := b.Field("posChangeSize").Count32()
 	msg.PosChanges = make([]CardInfo, posChangeSize)
 	for i := range msg.PosChanges {
 		msg.PosChanges[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 
-	monsterSetSize := utils.ReadUint32(b)
+	monsterSetSize := b.Field("monsterSetSize").Count32()
 	msg.MonsterSets = make([]CardInfo, monsterSetSize)
 	for i := range msg.MonsterSets {
 		msg.MonsterSets[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 
-	spellSetSize := utils.ReadUint32(b)
+	spellSetSize := b.Field("spellSetSize").Count32()
 	msg.SpellSets = make([]CardInfo, spellSetSize)
 	for i := range msg.SpellSets {
 		msg.SpellSets[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 
-	activateSize := utils.ReadUint32(b)
+	activateSize := b.Field("activateSize").Count32()
 	msg.Activate = make([]ChainInfo, activateSize)
 	for i := range msg.Activate {
 		msg.Activate[i] = ChainInfo{
-			Code:        int(utils.ReadUint32(b)),
-			Controller:  int(utils.ReadUint8(b)),
-			Location:    parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:    int(utils.ReadUint32(b)),
-			Description: utils.ReadUint64(b),
-			ClientMode:  utils.ReadUint8(b),
+			Code:        int(b.Field("Code").Uint32()),
+			Controller:  int(b.Field("Controller").Uint8()),
+			Location:    parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:    int(b.Field("Sequence").Uint32()),
+			Description: b.Field("Description").Uint64(),
+			ClientMode:  b.Field("ClientMode").Uint8(),
 		}
 	}
 
-	msg.ToBP = utils.ReadUint8(b) != 0
-	msg.ToEP = utils.ReadUint8(b) != 0
-	msg.Shuffle = utils.ReadUint8(b) != 0
+	msg.ToBP = b.Field("ToBP").Uint8() != 0
+	msg.ToEP = b.Field("ToEP").Uint8() != 0
+	msg.Shuffle = b.Field("Shuffle").Uint8() != 0
 	return
 }
 
@@ -643,15 +724,15 @@ type MessageSelectEffectYN struct {
 	Description uint64   `json:"description"`
 }
 
-func ReadMessageSelectEffectYN(b *bytes.Buffer) (msg MessageSelectEffectYN) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Code = utils.ReadUint32(b)
+func ReadMessageSelectEffectYN(b *utils.Reader) (msg MessageSelectEffectYN) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Code = b.Field("Code").Uint32()
 	loc := readCardLocation(b)
 	msg.Controller = loc.controller
 	msg.Location = parseCoreLocation(loc.location)
 	msg.Sequence = loc.sequence
 	msg.Position = parseCorePosition(loc.position)
-	msg.Description = utils.ReadUint64(b)
+	msg.Description = b.Field("Description").Uint64()
 	return
 }
 
@@ -664,9 +745,9 @@ type MessageSelectYesNo struct {
 	Description uint64 `json:"description"`
 }
 
-func ReadMessageSelectYesNo(b *bytes.Buffer) (msg MessageSelectYesNo) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Description = utils.ReadUint64(b)
+func ReadMessageSelectYesNo(b *utils.Reader) (msg MessageSelectYesNo) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Description = b.Field("Description").Uint64()
 	return
 }
 
@@ -679,13 +760,13 @@ type MessageSelectOption struct {
 	Options []uint64 `json:"options"`
 }
 
-func ReadMessageSelectOption(b *bytes.Buffer) (msg MessageSelectOption) {
-	msg.Player = int(utils.ReadUint8(b))
+func ReadMessageSelectOption(b *utils.Reader) (msg MessageSelectOption) {
+	msg.Player = int(b.Field("Player").Uint8())
 
-	optionsSize := utils.ReadUint8(b)
+	optionsSize := b.Field("optionsSize").Uint8()
 	msg.Options = make([]uint64, optionsSize)
 	for i := range msg.Options {
-		msg.Options[i] = utils.ReadUint64(b)
+		msg.Options[i] = b.Field("Options").Uint64()
 	}
 	return
 }
@@ -711,17 +792,17 @@ func parseCardLocation(loc cardLocation) CardLocation {
 	}
 }
 
-func ReadMessageSelectCard(b *bytes.Buffer) (msg MessageSelectCard) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Cancellable = utils.ReadUint8(b) != 0
-	msg.Min = int(utils.ReadUint32(b))
-	msg.Max = int(utils.ReadUint32(b))
+func ReadMessageSelectCard(b *utils.Reader) (msg MessageSelectCard) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Cancellable = b.Field("Cancellable").Uint8() != 0
+	msg.Min = int(b.Field("Min").Uint32())
+	msg.Max = int(b.Field("Max").Uint32())
 
-	cardsSize := utils.ReadUint32(b)
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]FieldCardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = FieldCardInfo{
-			Code:         int(utils.ReadUint32(b)),
+			Code:         int(b.Field("Code").Uint32()),
 			CardLocation: parseCardLocation(readCardLocation(b)),
 		}
 	}
@@ -741,21 +822,21 @@ type MessageSelectChain struct {
 	Chains           []CardChainInfo `json:"chains"`
 }
 
-func ReadMessageSelectChain(b *bytes.Buffer) (msg MessageSelectChain) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.SpeCount = int(utils.ReadUint8(b))
-	msg.Forced = utils.ReadUint8(b) != 0
-	msg.HintTimingPlayer = utils.ReadUint32(b)
-	msg.HintTimingOther = utils.ReadUint32(b)
+func ReadMessageSelectChain(b *utils.Reader) (msg MessageSelectChain) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.SpeCount = int(b.Field("SpeCount").Uint8())
+	msg.Forced = b.Field("Forced").Uint8() != 0
+	msg.HintTimingPlayer = b.Field("HintTimingPlayer").Uint32()
+	msg.HintTimingOther = b.Field("HintTimingOther").Uint32()
 
-	chainsSize := utils.ReadUint32(b)
+	chainsSize := b.Field("chainsSize").Count32()
 	msg.Chains = make([]CardChainInfo, chainsSize)
 	for i := range msg.Chains {
 		msg.Chains[i] = CardChainInfo{
-			Code:         int(utils.ReadUint32(b)),
+			Code:         int(b.Field("Code").Uint32()),
 			CardLocation: parseCardLocation(readCardLocation(b)),
-			Description:  utils.ReadUint64(b),
-			ClientMode:   utils.ReadUint8(b),
+			Description:  b.Field("Description").Uint64(),
+			ClientMode:   b.Field("ClientMode").Uint8(),
 		}
 	}
 	return
@@ -771,10 +852,10 @@ type MessageSelectPlace struct {
 	Places []Place `json:"places"`
 }
 
-func ReadMessageSelectPlace(b *bytes.Buffer) (msg MessageSelectPlace) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Count = int(utils.ReadUint8(b))
-	msg.Places = parsePlaceFlag(utils.ReadUint32(b))
+func ReadMessageSelectPlace(b *utils.Reader) (msg MessageSelectPlace) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Count = int(b.Field("Count").Uint8())
+	msg.Places = parsePlaceFlag(b.Field("Places").Uint32())
 	return
 }
 
@@ -788,10 +869,10 @@ type MessageSelectPosition struct {
 	Positions []Position `json:"positions"`
 }
 
-func ReadMessageSelectPosition(b *bytes.Buffer) (msg MessageSelectPosition) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Code = utils.ReadUint32(b)
-	msg.Positions = parseCorePositions(lib.Position(utils.ReadUint8(b)))
+func ReadMessageSelectPosition(b *utils.Reader) (msg MessageSelectPosition) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Code = b.Field("Code").Uint32()
+	msg.Positions = parseCorePositions(lib.Position(b.Field("Positions").Uint8()))
 	return
 }
 
@@ -807,21 +888,21 @@ type MessageSelectTribute struct {
 	Cards       []TributeCardInfo `json:"cards"`
 }
 
-func ReadMessageSelectTribute(b *bytes.Buffer) (msg MessageSelectTribute) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Cancellable = utils.ReadUint8(b) != 0
-	msg.Min = int(utils.ReadUint32(b))
-	msg.Max = int(utils.ReadUint32(b))
+func ReadMessageSelectTribute(b *utils.Reader) (msg MessageSelectTribute) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Cancellable = b.Field("Cancellable").Uint8() != 0
+	msg.Min = int(b.Field("Min").Uint32())
+	msg.Max = int(b.Field("Max").Uint32())
 
-	tributeSize := utils.ReadUint32(b)
+	tributeSize := b.Field("tributeSize").Count32()
 	msg.Cards = make([]TributeCardInfo, tributeSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = TributeCardInfo{
-			Code:         int(utils.ReadUint32(b)),
-			Controller:   int(utils.ReadUint8(b)),
-			Location:     parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:     int(utils.ReadUint32(b)),
-			ReleaseParam: int(utils.ReadUint8(b)),
+			Code:         int(b.Field("Code").Uint32()),
+			Controller:   int(b.Field("Controller").Uint8()),
+			Location:     parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:     int(b.Field("Sequence").Uint32()),
+			ReleaseParam: int(b.Field("ReleaseParam").Uint8()),
 		}
 	}
 	return
@@ -836,16 +917,16 @@ type MessageSortChain struct {
 	Cards  []CardInfo `json:"cards"`
 }
 
-func ReadMessageSortChain(b *bytes.Buffer) (msg MessageSortChain) {
-	msg.Player = int(utils.ReadUint8(b))
-	cardsSize := utils.ReadUint32(b)
+func ReadMessageSortChain(b *utils.Reader) (msg MessageSortChain) {
+	msg.Player = int(b.Field("Player").Uint8())
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]CardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 	return
@@ -862,19 +943,19 @@ type MessageSelectCounter struct {
 	Cards       []CounterCardInfo `json:"cards"`
 }
 
-func ReadMessageSelectCounter(b *bytes.Buffer) (msg MessageSelectCounter) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.CounterType = int(utils.ReadUint16(b))
-	msg.Count = int(utils.ReadUint16(b))
-	cardsSize := utils.ReadUint32(b)
+func ReadMessageSelectCounter(b *utils.Reader) (msg MessageSelectCounter) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.CounterType = int(b.Field("CounterType").Uint16())
+	msg.Count = int(b.Field("Count").Uint16())
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]CounterCardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = CounterCardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint8(b)),
-			Count:      int(utils.ReadUint16(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint8()),
+			Count:      int(b.Field("Count").Uint16()),
 		}
 	}
 	return
@@ -894,32 +975,32 @@ type MessageSelectSum struct {
 	Selects     []CounterCardInfo `json:"selects"`
 }
 
-func ReadMessageSelectSum(b *bytes.Buffer) (msg MessageSelectSum) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.HasMax = utils.ReadUint8(b) != 0
-	msg.Acc = int(utils.ReadUint32(b))
-	msg.Min = int(utils.ReadUint32(b))
-	msg.Max = int(utils.ReadUint32(b))
-	mustSelectsSize := utils.ReadUint32(b)
+func ReadMessageSelectSum(b *utils.Reader) (msg MessageSelectSum) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.HasMax = b.Field("HasMax").Uint8() != 0
+	msg.Acc = int(b.Field("Acc").Uint32())
+	msg.Min = int(b.Field("Min").Uint32())
+	msg.Max = int(b.Field("Max").Uint32())
+	mustSelectsSize := b.Field("mustSelectsSize").Count32()
 	msg.MustSelects = make([]CounterCardInfo, mustSelectsSize)
 	for i := range msg.MustSelects {
 		msg.MustSelects[i] = CounterCardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
-			Count:      int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
+			Count:      int(b.Field("Count").Uint32()),
 		}
 	}
-	selectsSize := utils.ReadUint32(b)
+	selectsSize := b.Field("selectsSize").Count32()
 	msg.Selects = make([]CounterCardInfo, selectsSize)
 	for i := range msg.Selects {
 		msg.Selects[i] = CounterCardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
-			Count:      int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
+			Count:      int(b.Field("Count").Uint32()),
 		}
 	}
 	return
@@ -935,10 +1016,10 @@ type MessageSelectDisfield struct {
 	Flag   uint32 `json:"flag"`
 }
 
-func ReadMessageSelectDisfield(b *bytes.Buffer) (msg MessageSelectDisfield) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Count = int(utils.ReadUint8(b))
-	msg.Flag = utils.ReadUint32(b)
+func ReadMessageSelectDisfield(b *utils.Reader) (msg MessageSelectDisfield) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Count = int(b.Field("Count").Uint8())
+	msg.Flag = b.Field("Flag").Uint32()
 	return
 }
 
@@ -951,16 +1032,16 @@ type MessageSortCard struct {
 	Cards  []CardInfo `json:"cards"`
 }
 
-func ReadMessageSortCard(b *bytes.Buffer) (msg MessageSortCard) {
-	msg.Player = int(utils.ReadUint8(b))
-	cardsSize := utils.ReadUint32(b)
+func ReadMessageSortCard(b *utils.Reader) (msg MessageSortCard) {
+	msg.Player = int(b.Field("Player").Uint8())
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]CardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 	return
@@ -980,26 +1061,26 @@ type MessageSelectUnselectCard struct {
 	Unselects   []FieldCardInfo `json:"unselects"`
 }
 
-func ReadMessageSelectUnselectCard(b *bytes.Buffer) (msg MessageSelectUnselectCard) {
-	msg.Player = int(utils.ReadUint8(b))
-	msg.Finishable = utils.ReadUint8(b) != 0
-	msg.Cancellable = utils.ReadUint8(b) != 0
-	msg.Min = int(utils.ReadUint32(b))
-	msg.Max = int(utils.ReadUint32(b))
+func ReadMessageSelectUnselectCard(b *utils.Reader) (msg MessageSelectUnselectCard) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.Finishable = b.Field("Finishable").Uint8() != 0
+	msg.Cancellable = b.Field("Cancellable").Uint8() != 0
+	msg.Min = int(b.Field("Min").Uint32())
+	msg.Max = int(b.Field("Max").Uint32())
 
-	selectsSize := utils.ReadUint32(b)
+	selectsSize := b.Field("selectsSize").Count32()
 	msg.Selects = make([]FieldCardInfo, selectsSize)
 	for i := range msg.Selects {
 		msg.Selects[i] = FieldCardInfo{
-			Code:         int(utils.ReadUint32(b)),
+			Code:         int(b.Field("Code").Uint32()),
 			CardLocation: parseCardLocation(readCardLocation(b)),
 		}
 	}
-	unselectsSize := utils.ReadUint32(b)
+	unselectsSize := b.Field("unselectsSize").Count32()
 	msg.Unselects = make([]FieldCardInfo, unselectsSize)
 	for i := range msg.Unselects {
 		msg.Unselects[i] = FieldCardInfo{
-			Code:         int(utils.ReadUint32(b)),
+			Code:         int(b.Field("Code").Uint32()),
 			CardLocation: parseCardLocation(readCardLocation(b)),
 		}
 	}
@@ -1015,16 +1096,16 @@ type MessageConfirmDeckTop struct {
 	Cards  []CardInfo `json:"cards"`
 }
 
-func ReadMessageConfirmDeckTop(b *bytes.Buffer) (msg MessageConfirmDeckTop) {
-	msg.Player = int(utils.ReadUint8(b))
-	cardsSize := utils.ReadUint32(b)
+func ReadMessageConfirmDeckTop(b *utils.Reader) (msg MessageConfirmDeckTop) {
+	msg.Player = int(b.Field("Player").Uint8())
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]CardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 	return
@@ -1039,16 +1120,16 @@ type MessageConfirmCards struct {
 	Cards  []CardInfo `json:"cards"`
 }
 
-func ReadMessageConfirmCards(b *bytes.Buffer) (msg MessageConfirmCards) {
-	msg.Player = int(utils.ReadUint8(b))
-	cardsSize := utils.ReadUint32(b)
+func ReadMessageConfirmCards(b *utils.Reader) (msg MessageConfirmCards) {
+	msg.Player = int(b.Field("Player").Uint8())
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]CardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = CardInfo{
-			Code:       int(utils.ReadUint32(b)),
-			Controller: int(utils.ReadUint8(b)),
-			Location:   parseCoreLocation(lib.Location(utils.ReadUint8(b))),
-			Sequence:   int(utils.ReadUint32(b)),
+			Code:       int(b.Field("Code").Uint32()),
+			Controller: int(b.Field("Controller").Uint8()),
+			Location:   parseCoreLocation(lib.Location(b.Field("Location").Uint8())),
+			Sequence:   int(b.Field("Sequence").Uint32()),
 		}
 	}
 	return
@@ -1062,8 +1143,8 @@ type MessageShuffleDeck struct {
 	Player int `json:"player"`
 }
 
-func ReadMessageShuffleDeck(b *bytes.Buffer) (msg MessageShuffleDeck) {
-	msg.Player = int(utils.ReadUint8(b))
+func ReadMessageShuffleDeck(b *utils.Reader) (msg MessageShuffleDeck) {
+	msg.Player = int(b.Field("Player").Uint8())
 	return
 }
 
@@ -1074,9 +1155,9 @@ func (MessageShuffleDeck) messageType() MessageType {
 type MessageShuffleHand struct {
 }
 
-func ReadMessageShuffleHand(b *bytes.Buffer) (msg MessageShuffleHand) {
+func ReadMessageShuffleHand(*utils.Reader) (msg MessageShuffleHand, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageShuffleHand) messageType() MessageType {
@@ -1086,9 +1167,9 @@ func (MessageShuffleHand) messageType() MessageType {
 type MessageRefreshDeck struct {
 }
 
-func ReadMessageRefreshDeck(b *bytes.Buffer) (msg MessageRefreshDeck) {
+func ReadMessageRefreshDeck(*utils.Reader) (msg MessageRefreshDeck, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageRefreshDeck) messageType() MessageType {
@@ -1098,9 +1179,9 @@ func (MessageRefreshDeck) messageType() MessageType {
 type MessageSwapGraveDeck struct {
 }
 
-func ReadMessageSwapGraveDeck(b *bytes.Buffer) (msg MessageSwapGraveDeck) {
+func ReadMessageSwapGraveDeck(*utils.Reader) (msg MessageSwapGraveDeck, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageSwapGraveDeck) messageType() MessageType {
@@ -1110,9 +1191,9 @@ func (MessageSwapGraveDeck) messageType() MessageType {
 type MessageShuffleSetCard struct {
 }
 
-func ReadMessageShuffleSetCard(b *bytes.Buffer) (msg MessageShuffleSetCard) {
+func ReadMessageShuffleSetCard(*utils.Reader) (msg MessageShuffleSetCard, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageShuffleSetCard) messageType() MessageType {
@@ -1122,9 +1203,9 @@ func (MessageShuffleSetCard) messageType() MessageType {
 type MessageReverseDeck struct {
 }
 
-func ReadMessageReverseDeck(b *bytes.Buffer) (msg MessageReverseDeck) {
+func ReadMessageReverseDeck(*utils.Reader) (msg MessageReverseDeck, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageReverseDeck) messageType() MessageType {
@@ -1134,9 +1215,9 @@ func (MessageReverseDeck) messageType() MessageType {
 type MessageDeckTop struct {
 }
 
-func ReadMessageDeckTop(b *bytes.Buffer) (msg MessageDeckTop) {
+func ReadMessageDeckTop(*utils.Reader) (msg MessageDeckTop, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageDeckTop) messageType() MessageType {
@@ -1146,9 +1227,9 @@ func (MessageDeckTop) messageType() MessageType {
 type MessageShuffleExtra struct {
 }
 
-func ReadMessageShuffleExtra(b *bytes.Buffer) (msg MessageShuffleExtra) {
+func ReadMessageShuffleExtra(*utils.Reader) (msg MessageShuffleExtra, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageShuffleExtra) messageType() MessageType {
@@ -1159,8 +1240,8 @@ type MessageNewTurn struct {
 	Player int `json:"player"`
 }
 
-func ReadMessageNewTurn(b *bytes.Buffer) (msg MessageNewTurn) {
-	msg.Player = int(utils.ReadUint8(b))
+func ReadMessageNewTurn(b *utils.Reader) (msg MessageNewTurn) {
+	msg.Player = int(b.Field("Player").Uint8())
 	return
 }
 
@@ -1173,8 +1254,8 @@ type MessageNewPhase struct {
 	DetailedPhase DetailedPhase `json:"detailed_phase"`
 }
 
-func ReadMessageNewPhase(b *bytes.Buffer) (msg MessageNewPhase) {
-	phase := lib.Phase(utils.ReadUint16(b))
+func ReadMessageNewPhase(b *utils.Reader) (msg MessageNewPhase) {
+	phase := lib.Phase(b.Field("phase").Uint16())
 	msg.DetailedPhase = parseCorePhaseDetailed(phase)
 	msg.Phase = parseCorePhase(phase)
 	return
@@ -1187,9 +1268,9 @@ func (MessageNewPhase) messageType() MessageType {
 type MessageConfirmExtraTop struct {
 }
 
-func ReadMessageConfirmExtraTop(b *bytes.Buffer) (msg MessageConfirmExtraTop) {
+func ReadMessageConfirmExtraTop(*utils.Reader) (msg MessageConfirmExtraTop, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageConfirmExtraTop) messageType() MessageType {
@@ -1202,11 +1283,11 @@ type MessageMove struct {
 	Reason   uint32        `json:"reason"`
 }
 
-func ReadMessageMove(b *bytes.Buffer) (msg MessageMove) {
-	msg.Card.Code = int(utils.ReadUint32(b))
+func ReadMessageMove(b *utils.Reader) (msg MessageMove) {
+	msg.Card.Code = int(b.Field("Code").Uint32())
 	msg.Card.CardLocation = parseCardLocation(readCardLocation(b))
 	msg.Previous = parseCardLocation(readCardLocation(b))
-	msg.Reason = utils.ReadUint32(b)
+	msg.Reason = b.Field("Reason").Uint32()
 	return
 }
 
@@ -1217,9 +1298,9 @@ func (MessageMove) messageType() MessageType {
 type MessagePosChange struct {
 }
 
-func ReadMessagePosChange(b *bytes.Buffer) (msg MessagePosChange) {
+func ReadMessagePosChange(*utils.Reader) (msg MessagePosChange, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessagePosChange) messageType() MessageType {
@@ -1229,9 +1310,9 @@ func (MessagePosChange) messageType() MessageType {
 type MessageSet struct {
 }
 
-func ReadMessageSet(b *bytes.Buffer) (msg MessageSet) {
+func ReadMessageSet(*utils.Reader) (msg MessageSet, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageSet) messageType() MessageType {
@@ -1241,9 +1322,9 @@ func (MessageSet) messageType() MessageType {
 type MessageSwap struct {
 }
 
-func ReadMessageSwap(b *bytes.Buffer) (msg MessageSwap) {
+func ReadMessageSwap(*utils.Reader) (msg MessageSwap, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageSwap) messageType() MessageType {
@@ -1253,9 +1334,9 @@ func (MessageSwap) messageType() MessageType {
 type MessageFieldDisabled struct {
 }
 
-func ReadMessageFieldDisabled(b *bytes.Buffer) (msg MessageFieldDisabled) {
+func ReadMessageFieldDisabled(*utils.Reader) (msg MessageFieldDisabled, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageFieldDisabled) messageType() MessageType {
@@ -1266,8 +1347,8 @@ type MessageSummoning struct {
 	Card FieldCardInfo `json:"card"`
 }
 
-func ReadMessageSummoning(b *bytes.Buffer) (msg MessageSummoning) {
-	msg.Card.Code = int(utils.ReadUint32(b))
+func ReadMessageSummoning(b *utils.Reader) (msg MessageSummoning) {
+	msg.Card.Code = int(b.Field("Code").Uint32())
 	msg.Card.CardLocation = parseCardLocation(readCardLocation(b))
 	return
 }
@@ -1278,7 +1359,7 @@ func (MessageSummoning) messageType() MessageType {
 
 type MessageSummoned struct{}
 
-func ReadMessageSummoned(*bytes.Buffer) (msg MessageSummoned) {
+func ReadMessageSummoned(*utils.Reader) (msg MessageSummoned) {
 	return
 }
 
@@ -1290,8 +1371,8 @@ type MessageSPSummoning struct {
 	Card FieldCardInfo `json:"card"`
 }
 
-func ReadMessageSPSummoning(b *bytes.Buffer) (msg MessageSPSummoning) {
-	msg.Card.Code = int(utils.ReadUint32(b))
+func ReadMessageSPSummoning(b *utils.Reader) (msg MessageSPSummoning) {
+	msg.Card.Code = int(b.Field("Code").Uint32())
 	msg.Card.CardLocation = parseCardLocation(readCardLocation(b))
 	return
 }
@@ -1302,7 +1383,7 @@ func (MessageSPSummoning) messageType() MessageType {
 
 type MessageSPSummoned struct{}
 
-func ReadMessageSPSummoned(*bytes.Buffer) (msg MessageSPSummoned) {
+func ReadMessageSPSummoned(*utils.Reader) (msg MessageSPSummoned) {
 	return
 }
 
@@ -1313,9 +1394,9 @@ func (MessageSPSummoned) messageType() MessageType {
 type MessageFlipSummoning struct {
 }
 
-func ReadMessageFlipSummoning(b *bytes.Buffer) (msg MessageFlipSummoning) {
+func ReadMessageFlipSummoning(*utils.Reader) (msg MessageFlipSummoning, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageFlipSummoning) messageType() MessageType {
@@ -1325,9 +1406,9 @@ func (MessageFlipSummoning) messageType() MessageType {
 type MessageFlipSummoned struct {
 }
 
-func ReadMessageFlipSummoned(b *bytes.Buffer) (msg MessageFlipSummoned) {
+func ReadMessageFlipSummoned(*utils.Reader) (msg MessageFlipSummoned, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageFlipSummoned) messageType() MessageType {
@@ -1343,14 +1424,14 @@ type MessageChaining struct {
 	Count             int           `json:"count"`
 }
 
-func ReadMessageChaining(b *bytes.Buffer) (msg MessageChaining) {
-	msg.Card.Code = int(utils.ReadUint32(b))
+func ReadMessageChaining(b *utils.Reader) (msg MessageChaining) {
+	msg.Card.Code = int(b.Field("Code").Uint32())
 	msg.Card.CardLocation = parseCardLocation(readCardLocation(b))
-	msg.TriggerController = int(utils.ReadUint8(b))
-	msg.TriggerLocation = parseCoreLocation(lib.Location(utils.ReadUint8(b)))
-	msg.TriggerSequence = int(utils.ReadUint8(b))
-	msg.Description = utils.ReadUint64(b)
-	msg.Count = int(utils.ReadUint32(b))
+	msg.TriggerController = int(b.Field("TriggerController").Uint8())
+	msg.TriggerLocation = parseCoreLocation(lib.Location(b.Field("TriggerLocation").Uint8()))
+	msg.TriggerSequence = int(b.Field("TriggerSequence").Uint8())
+	msg.Description = b.Field("Description").Uint64()
+	msg.Count = int(b.Field("Count").Uint32())
 	return
 }
 
@@ -1362,8 +1443,8 @@ type MessageChained struct {
 	Count int `json:"count"`
 }
 
-func ReadMessageChained(b *bytes.Buffer) (msg MessageChained) {
-	msg.Count = int(utils.ReadUint8(b))
+func ReadMessageChained(b *utils.Reader) (msg MessageChained) {
+	msg.Count = int(b.Field("Count").Uint8())
 	return
 }
 
@@ -1375,8 +1456,8 @@ type MessageChainSolving struct {
 	Count int `json:"count"`
 }
 
-func ReadMessageChainSolving(b *bytes.Buffer) (msg MessageChainSolving) {
-	msg.Count = int(utils.ReadUint8(b))
+func ReadMessageChainSolving(b *utils.Reader) (msg MessageChainSolving) {
+	msg.Count = int(b.Field("Count").Uint8())
 	return
 }
 
@@ -1388,8 +1469,8 @@ type MessageChainSolved struct {
 	Count int `json:"count"`
 }
 
-func ReadMessageChainSolved(b *bytes.Buffer) (msg MessageChainSolved) {
-	msg.Count = int(utils.ReadUint8(b))
+func ReadMessageChainSolved(b *utils.Reader) (msg MessageChainSolved) {
+	msg.Count = int(b.Field("Count").Uint8())
 	return
 }
 
@@ -1399,7 +1480,7 @@ func (MessageChainSolved) messageType() MessageType {
 
 type MessageChainEnd struct{}
 
-func ReadMessageChainEnd(*bytes.Buffer) (msg MessageChainEnd) {
+func ReadMessageChainEnd(*utils.Reader) (msg MessageChainEnd) {
 	return
 }
 
@@ -1410,9 +1491,9 @@ func (MessageChainEnd) messageType() MessageType {
 type MessageChainNegated struct {
 }
 
-func ReadMessageChainNegated(b *bytes.Buffer) (msg MessageChainNegated) {
+func ReadMessageChainNegated(*utils.Reader) (msg MessageChainNegated, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageChainNegated) messageType() MessageType {
@@ -1422,9 +1503,9 @@ func (MessageChainNegated) messageType() MessageType {
 type MessageChainDisabled struct {
 }
 
-func ReadMessageChainDisabled(b *bytes.Buffer) (msg MessageChainDisabled) {
+func ReadMessageChainDisabled(*utils.Reader) (msg MessageChainDisabled, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageChainDisabled) messageType() MessageType {
@@ -1434,9 +1515,9 @@ func (MessageChainDisabled) messageType() MessageType {
 type MessageCardSelected struct {
 }
 
-func ReadMessageCardSelected(b *bytes.Buffer) (msg MessageCardSelected) {
+func ReadMessageCardSelected(*utils.Reader) (msg MessageCardSelected, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageCardSelected) messageType() MessageType {
@@ -1446,9 +1527,9 @@ func (MessageCardSelected) messageType() MessageType {
 type MessageRandomSelected struct {
 }
 
-func ReadMessageRandomSelected(b *bytes.Buffer) (msg MessageRandomSelected) {
+func ReadMessageRandomSelected(*utils.Reader) (msg MessageRandomSelected, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageRandomSelected) messageType() MessageType {
@@ -1459,8 +1540,8 @@ type MessageBecomeTarget struct {
 	Targets []CardLocation `json:"targets"`
 }
 
-func ReadMessageBecomeTarget(b *bytes.Buffer) (msg MessageBecomeTarget) {
-	targetsLen := utils.ReadUint32(b)
+func ReadMessageBecomeTarget(b *utils.Reader) (msg MessageBecomeTarget) {
+	targetsLen := b.Field("targetsLen").Count32()
 	msg.Targets = make([]CardLocation, targetsLen)
 	for i := range msg.Targets {
 		msg.Targets[i] = parseCardLocation(readCardLocation(b))
@@ -1477,14 +1558,14 @@ type MessageDraw struct {
 	Cards  []DrawnCardInfo `json:"cards"`
 }
 
-func ReadMessageDraw(b *bytes.Buffer) (msg MessageDraw) {
-	msg.Player = int(utils.ReadUint8(b))
-	cardsSize := utils.ReadUint32(b)
+func ReadMessageDraw(b *utils.Reader) (msg MessageDraw) {
+	msg.Player = int(b.Field("Player").Uint8())
+	cardsSize := b.Field("cardsSize").Count32()
 	msg.Cards = make([]DrawnCardInfo, cardsSize)
 	for i := range msg.Cards {
 		msg.Cards[i] = DrawnCardInfo{
-			Code:     int(utils.ReadUint32(b)),
-			Position: parseCorePosition(lib.Position(utils.ReadUint32(b))).Face(),
+			Code:     int(b.Field("Code").Uint32()),
+			Position: parseCorePosition(lib.Position(b.Field("Position").Uint32())).Face(),
 		}
 	}
 	return
@@ -1497,9 +1578,9 @@ func (MessageDraw) messageType() MessageType {
 type MessageDamage struct {
 }
 
-func ReadMessageDamage(b *bytes.Buffer) (msg MessageDamage) {
+func ReadMessageDamage(*utils.Reader) (msg MessageDamage, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageDamage) messageType() MessageType {
@@ -1509,9 +1590,9 @@ func (MessageDamage) messageType() MessageType {
 type MessageRecover struct {
 }
 
-func ReadMessageRecover(b *bytes.Buffer) (msg MessageRecover) {
+func ReadMessageRecover(*utils.Reader) (msg MessageRecover, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageRecover) messageType() MessageType {
@@ -1521,9 +1602,9 @@ func (MessageRecover) messageType() MessageType {
 type MessageEquip struct {
 }
 
-func ReadMessageEquip(b *bytes.Buffer) (msg MessageEquip) {
+func ReadMessageEquip(*utils.Reader) (msg MessageEquip, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageEquip) messageType() MessageType {
@@ -1533,9 +1614,9 @@ func (MessageEquip) messageType() MessageType {
 type MessageLPUpdate struct {
 }
 
-func ReadMessageLPUpdate(b *bytes.Buffer) (msg MessageLPUpdate) {
+func ReadMessageLPUpdate(*utils.Reader) (msg MessageLPUpdate, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageLPUpdate) messageType() MessageType {
@@ -1545,9 +1626,9 @@ func (MessageLPUpdate) messageType() MessageType {
 type MessageUnequip struct {
 }
 
-func ReadMessageUnequip(b *bytes.Buffer) (msg MessageUnequip) {
+func ReadMessageUnequip(*utils.Reader) (msg MessageUnequip, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageUnequip) messageType() MessageType {
@@ -1557,9 +1638,9 @@ func (MessageUnequip) messageType() MessageType {
 type MessageCardTarget struct {
 }
 
-func ReadMessageCardTarget(b *bytes.Buffer) (msg MessageCardTarget) {
+func ReadMessageCardTarget(*utils.Reader) (msg MessageCardTarget, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageCardTarget) messageType() MessageType {
@@ -1569,9 +1650,9 @@ func (MessageCardTarget) messageType() MessageType {
 type MessageCancelTarget struct {
 }
 
-func ReadMessageCancelTarget(b *bytes.Buffer) (msg MessageCancelTarget) {
+func ReadMessageCancelTarget(*utils.Reader) (msg MessageCancelTarget, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageCancelTarget) messageType() MessageType {
@@ -1581,9 +1662,9 @@ func (MessageCancelTarget) messageType() MessageType {
 type MessagePayLPCost struct {
 }
 
-func ReadMessagePayLPCost(b *bytes.Buffer) (msg MessagePayLPCost) {
+func ReadMessagePayLPCost(*utils.Reader) (msg MessagePayLPCost, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessagePayLPCost) messageType() MessageType {
@@ -1593,9 +1674,9 @@ func (MessagePayLPCost) messageType() MessageType {
 type MessageAddCounter struct {
 }
 
-func ReadMessageAddCounter(b *bytes.Buffer) (msg MessageAddCounter) {
+func ReadMessageAddCounter(*utils.Reader) (msg MessageAddCounter, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAddCounter) messageType() MessageType {
@@ -1605,9 +1686,9 @@ func (MessageAddCounter) messageType() MessageType {
 type MessageRemoveCounter struct {
 }
 
-func ReadMessageRemoveCounter(b *bytes.Buffer) (msg MessageRemoveCounter) {
+func ReadMessageRemoveCounter(*utils.Reader) (msg MessageRemoveCounter, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageRemoveCounter) messageType() MessageType {
@@ -1617,9 +1698,9 @@ func (MessageRemoveCounter) messageType() MessageType {
 type MessageAttack struct {
 }
 
-func ReadMessageAttack(b *bytes.Buffer) (msg MessageAttack) {
+func ReadMessageAttack(*utils.Reader) (msg MessageAttack, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAttack) messageType() MessageType {
@@ -1629,9 +1710,9 @@ func (MessageAttack) messageType() MessageType {
 type MessageBattle struct {
 }
 
-func ReadMessageBattle(b *bytes.Buffer) (msg MessageBattle) {
+func ReadMessageBattle(*utils.Reader) (msg MessageBattle, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageBattle) messageType() MessageType {
@@ -1641,9 +1722,9 @@ func (MessageBattle) messageType() MessageType {
 type MessageAttackDisabled struct {
 }
 
-func ReadMessageAttackDisabled(b *bytes.Buffer) (msg MessageAttackDisabled) {
+func ReadMessageAttackDisabled(*utils.Reader) (msg MessageAttackDisabled, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAttackDisabled) messageType() MessageType {
@@ -1653,9 +1734,9 @@ func (MessageAttackDisabled) messageType() MessageType {
 type MessageDamageStepStart struct {
 }
 
-func ReadMessageDamageStepStart(b *bytes.Buffer) (msg MessageDamageStepStart) {
+func ReadMessageDamageStepStart(*utils.Reader) (msg MessageDamageStepStart, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageDamageStepStart) messageType() MessageType {
@@ -1665,9 +1746,9 @@ func (MessageDamageStepStart) messageType() MessageType {
 type MessageDamageStepEnd struct {
 }
 
-func ReadMessageDamageStepEnd(b *bytes.Buffer) (msg MessageDamageStepEnd) {
+func ReadMessageDamageStepEnd(*utils.Reader) (msg MessageDamageStepEnd, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageDamageStepEnd) messageType() MessageType {
@@ -1677,9 +1758,9 @@ func (MessageDamageStepEnd) messageType() MessageType {
 type MessageMissedEffect struct {
 }
 
-func ReadMessageMissedEffect(b *bytes.Buffer) (msg MessageMissedEffect) {
+func ReadMessageMissedEffect(*utils.Reader) (msg MessageMissedEffect, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageMissedEffect) messageType() MessageType {
@@ -1689,9 +1770,9 @@ func (MessageMissedEffect) messageType() MessageType {
 type MessageBeChainTarget struct {
 }
 
-func ReadMessageBeChainTarget(b *bytes.Buffer) (msg MessageBeChainTarget) {
+func ReadMessageBeChainTarget(*utils.Reader) (msg MessageBeChainTarget, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageBeChainTarget) messageType() MessageType {
@@ -1701,9 +1782,9 @@ func (MessageBeChainTarget) messageType() MessageType {
 type MessageCreateRelation struct {
 }
 
-func ReadMessageCreateRelation(b *bytes.Buffer) (msg MessageCreateRelation) {
+func ReadMessageCreateRelation(*utils.Reader) (msg MessageCreateRelation, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageCreateRelation) messageType() MessageType {
@@ -1713,9 +1794,9 @@ func (MessageCreateRelation) messageType() MessageType {
 type MessageReleaseRelation struct {
 }
 
-func ReadMessageReleaseRelation(b *bytes.Buffer) (msg MessageReleaseRelation) {
+func ReadMessageReleaseRelation(*utils.Reader) (msg MessageReleaseRelation, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageReleaseRelation) messageType() MessageType {
@@ -1725,9 +1806,9 @@ func (MessageReleaseRelation) messageType() MessageType {
 type MessageTossCoin struct {
 }
 
-func ReadMessageTossCoin(b *bytes.Buffer) (msg MessageTossCoin) {
+func ReadMessageTossCoin(*utils.Reader) (msg MessageTossCoin, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageTossCoin) messageType() MessageType {
@@ -1737,9 +1818,9 @@ func (MessageTossCoin) messageType() MessageType {
 type MessageTossDice struct {
 }
 
-func ReadMessageTossDice(b *bytes.Buffer) (msg MessageTossDice) {
+func ReadMessageTossDice(*utils.Reader) (msg MessageTossDice, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageTossDice) messageType() MessageType {
@@ -1749,9 +1830,9 @@ func (MessageTossDice) messageType() MessageType {
 type MessageRockPaperScissors struct {
 }
 
-func ReadMessageRockPaperScissors(b *bytes.Buffer) (msg MessageRockPaperScissors) {
+func ReadMessageRockPaperScissors(*utils.Reader) (msg MessageRockPaperScissors, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageRockPaperScissors) messageType() MessageType {
@@ -1761,9 +1842,9 @@ func (MessageRockPaperScissors) messageType() MessageType {
 type MessageHandRes struct {
 }
 
-func ReadMessageHandRes(b *bytes.Buffer) (msg MessageHandRes) {
+func ReadMessageHandRes(*utils.Reader) (msg MessageHandRes, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageHandRes) messageType() MessageType {
@@ -1773,9 +1854,9 @@ func (MessageHandRes) messageType() MessageType {
 type MessageAnnounceRace struct {
 }
 
-func ReadMessageAnnounceRace(b *bytes.Buffer) (msg MessageAnnounceRace) {
+func ReadMessageAnnounceRace(*utils.Reader) (msg MessageAnnounceRace, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAnnounceRace) messageType() MessageType {
@@ -1785,9 +1866,9 @@ func (MessageAnnounceRace) messageType() MessageType {
 type MessageAnnounceAttribute struct {
 }
 
-func ReadMessageAnnounceAttribute(b *bytes.Buffer) (msg MessageAnnounceAttribute) {
+func ReadMessageAnnounceAttribute(*utils.Reader) (msg MessageAnnounceAttribute, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAnnounceAttribute) messageType() MessageType {
@@ -1797,9 +1878,9 @@ func (MessageAnnounceAttribute) messageType() MessageType {
 type MessageAnnounceCard struct {
 }
 
-func ReadMessageAnnounceCard(b *bytes.Buffer) (msg MessageAnnounceCard) {
+func ReadMessageAnnounceCard(*utils.Reader) (msg MessageAnnounceCard, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAnnounceCard) messageType() MessageType {
@@ -1809,9 +1890,9 @@ func (MessageAnnounceCard) messageType() MessageType {
 type MessageAnnounceNumber struct {
 }
 
-func ReadMessageAnnounceNumber(b *bytes.Buffer) (msg MessageAnnounceNumber) {
+func ReadMessageAnnounceNumber(*utils.Reader) (msg MessageAnnounceNumber, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageAnnounceNumber) messageType() MessageType {
@@ -1821,9 +1902,9 @@ func (MessageAnnounceNumber) messageType() MessageType {
 type MessageCardHint struct {
 }
 
-func ReadMessageCardHint(b *bytes.Buffer) (msg MessageCardHint) {
+func ReadMessageCardHint(*utils.Reader) (msg MessageCardHint, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageCardHint) messageType() MessageType {
@@ -1831,11 +1912,38 @@ func (MessageCardHint) messageType() MessageType {
 }
 
 type MessageTagSwap struct {
-}
-
-func ReadMessageTagSwap(b *bytes.Buffer) (msg MessageTagSwap) {
-	// TODO: implement
-	panic("not implemented")
+	Player               int             `json:"player"`
+	DeckCount            int             `json:"deck_count"`
+	ExtraDeckCount       int             `json:"extra_deck_count"`
+	ExtraDeckFaceUpCount int             `json:"extra_deck_face_up_count"`
+	DeckTop              int             `json:"deck_top"`
+	Hand                 []DrawnCardInfo `json:"hand"`
+	ExtraDeck            []DrawnCardInfo `json:"extra_deck"`
+}
+
+func ReadMessageTagSwap(b *utils.Reader) (msg MessageTagSwap) {
+	msg.Player = int(b.Field("Player").Uint8())
+	msg.DeckCount = int(b.Field("DeckCount").Uint32())
+	msg.ExtraDeckCount = b.Field("ExtraDeckCount").Count32()
+	msg.ExtraDeckFaceUpCount = int(b.Field("ExtraDeckFaceUpCount").Uint32())
+	handSize := b.Field("handSize").Count32()
+	msg.DeckTop = int(b.Field("DeckTop").Uint32())
+
+	msg.Hand = make([]DrawnCardInfo, handSize)
+	for i := range msg.Hand {
+		msg.Hand[i] = DrawnCardInfo{
+			Code:     int(b.Field("Code").Uint32()),
+			Position: parseCorePosition(lib.Position(b.Field("Position").Uint32())).Face(),
+		}
+	}
+	msg.ExtraDeck = make([]DrawnCardInfo, msg.ExtraDeckCount)
+	for i := range msg.ExtraDeck {
+		msg.ExtraDeck[i] = DrawnCardInfo{
+			Code:     int(b.Field("Code").Uint32()),
+			Position: parseCorePosition(lib.Position(b.Field("Position").Uint32())).Face(),
+		}
+	}
+	return
 }
 
 func (MessageTagSwap) messageType() MessageType {
@@ -1843,11 +1951,82 @@ func (MessageTagSwap) messageType() MessageType {
 }
 
 type MessageReloadField struct {
+	DuelOptions uint32               `json:"duel_options"`
+	Players     [2]ReloadFieldPlayer `json:"players"`
+	Chain       []ReloadFieldChain   `json:"chain"`
 }
 
-func ReadMessageReloadField(b *bytes.Buffer) (msg MessageReloadField) {
-	// TODO: implement
-	panic("not implemented")
+type ReloadFieldPlayer struct {
+	LP                   int                 `json:"lp"`
+	Monsters             [7]*ReloadFieldCard `json:"monsters"`
+	Spells               [8]*ReloadFieldCard `json:"spells"`
+	DeckCount            int                 `json:"deck_count"`
+	HandCount            int                 `json:"hand_count"`
+	GraveCount           int                 `json:"grave_count"`
+	BanishedCount        int                 `json:"banished_count"`
+	ExtraDeckCount       int                 `json:"extra_deck_count"`
+	ExtraDeckFaceUpCount int                 `json:"extra_deck_face_up_count"`
+}
+
+type ReloadFieldCard struct {
+	Position  Position `json:"position"`
+	Materials int      `json:"materials"`
+}
+
+type ReloadFieldChain struct {
+	Card              FieldCardInfo `json:"card"`
+	TriggerController int           `json:"trigger_controller"`
+	TriggerLocation   Location      `json:"trigger_location"`
+	TriggerSequence   int           `json:"trigger_sequence"`
+	Description       uint64        `json:"description"`
+}
+
+func ReadMessageReloadField(b *utils.Reader) (msg MessageReloadField) {
+	msg.DuelOptions = b.Field("DuelOptions").Uint32()
+	for i := range msg.Players {
+		readReloadFieldPlayer(b, &msg.Players[i])
+	}
+	chainSize := b.Field("chainSize").Count32()
+	msg.Chain = make([]ReloadFieldChain, chainSize)
+	for i := range msg.Chain {
+		msg.Chain[i] = ReloadFieldChain{
+			Card: FieldCardInfo{
+				Code:         int(b.Field("Code").Uint32()),
+				CardLocation: parseCardLocation(readCardLocation(b)),
+			},
+			TriggerController: int(b.Field("TriggerController").Uint8()),
+			TriggerLocation:   parseCoreLocation(lib.Location(b.Field("TriggerLocation").Uint8())),
+			TriggerSequence:   int(b.Field("TriggerSequence").Uint32()),
+			Description:       b.Field("Description").Uint64(),
+		}
+	}
+	return
+}
+
+func readReloadFieldPlayer(b *utils.Reader, player *ReloadFieldPlayer) {
+	player.LP = int(b.Field("LP").Uint32())
+	for i := range player.Monsters {
+		player.Monsters[i] = readReloadFieldCard(b)
+	}
+	for i := range player.Spells {
+		player.Spells[i] = readReloadFieldCard(b)
+	}
+	player.DeckCount = int(b.Field("DeckCount").Uint32())
+	player.HandCount = int(b.Field("HandCount").Uint32())
+	player.GraveCount = int(b.Field("GraveCount").Uint32())
+	player.BanishedCount = int(b.Field("BanishedCount").Uint32())
+	player.ExtraDeckCount = int(b.Field("ExtraDeckCount").Uint32())
+	player.ExtraDeckFaceUpCount = int(b.Field("ExtraDeckFaceUpCount").Uint32())
+}
+
+func readReloadFieldCard(b *utils.Reader) *ReloadFieldCard {
+	if b.Field("card").Uint8() == 0 {
+		return nil
+	}
+	return &ReloadFieldCard{
+		Position:  parseCorePosition(lib.Position(b.Field("Position").Uint8())),
+		Materials: int(b.Field("Materials").Uint32()),
+	}
 }
 
 func (MessageReloadField) messageType() MessageType {
@@ -1855,11 +2034,12 @@ func (MessageReloadField) messageType() MessageType {
 }
 
 type MessageAIName struct {
+	Name string `json:"name"`
 }
 
-func ReadMessageAIName(b *bytes.Buffer) (msg MessageAIName) {
-	// TODO: implement
-	panic("not implemented")
+func ReadMessageAIName(b *utils.Reader) (msg MessageAIName) {
+	msg.Name = readString(b)
+	return
 }
 
 func (MessageAIName) messageType() MessageType {
@@ -1867,11 +2047,12 @@ func (MessageAIName) messageType() MessageType {
 }
 
 type MessageShowHint struct {
+	Hint string `json:"hint"`
 }
 
-func ReadMessageShowHint(b *bytes.Buffer) (msg MessageShowHint) {
-	// TODO: implement
-	panic("not implemented")
+func ReadMessageShowHint(b *utils.Reader) (msg MessageShowHint) {
+	msg.Hint = readString(b)
+	return
 }
 
 func (MessageShowHint) messageType() MessageType {
@@ -1881,9 +2062,9 @@ func (MessageShowHint) messageType() MessageType {
 type MessagePlayerHint struct {
 }
 
-func ReadMessagePlayerHint(b *bytes.Buffer) (msg MessagePlayerHint) {
+func ReadMessagePlayerHint(*utils.Reader) (msg MessagePlayerHint, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessagePlayerHint) messageType() MessageType {
@@ -1893,9 +2074,9 @@ func (MessagePlayerHint) messageType() MessageType {
 type MessageMatchKill struct {
 }
 
-func ReadMessageMatchKill(b *bytes.Buffer) (msg MessageMatchKill) {
+func ReadMessageMatchKill(*utils.Reader) (msg MessageMatchKill, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageMatchKill) messageType() MessageType {
@@ -1905,9 +2086,9 @@ func (MessageMatchKill) messageType() MessageType {
 type MessageCustomMessage struct {
 }
 
-func ReadMessageCustomMessage(b *bytes.Buffer) (msg MessageCustomMessage) {
+func ReadMessageCustomMessage(*utils.Reader) (msg MessageCustomMessage, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageCustomMessage) messageType() MessageType {
@@ -1917,9 +2098,9 @@ func (MessageCustomMessage) messageType() MessageType {
 type MessageRemoveCards struct {
 }
 
-func ReadMessageRemoveCards(b *bytes.Buffer) (msg MessageRemoveCards) {
+func ReadMessageRemoveCards(*utils.Reader) (msg MessageRemoveCards, err error) {
 	// TODO: implement
-	panic("not implemented")
+	return msg, ErrNotImplemented
 }
 
 func (MessageRemoveCards) messageType() MessageType {
@@ -0,0 +1,112 @@
+package ocgcore_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"ocgcore"
+	"ocgcore/fake"
+	"ocgcore/lib"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadMessageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		ok   bool // a message is returned along with the error
+		err  string
+	}{
+		{"empty", nil, false, "empty message"},
+		{"unknown", []byte{0xff}, false, "unhandled message 255"},
+		{"truncated", fake.Message(lib.MessageHint, uint8(1), uint8(0), uint32(5)), false, "message hint truncated at offset 3: expected Desc"},
+		{"trailing", fake.Message(lib.MessageWin, uint8(0), uint8(0), uint8(1)), true, "message win has 1 trailing bytes"},
+		{"count", fake.Message(lib.MessageDraw, uint8(0), uint32(0xffffffff)), false, "expected cardsSize"},
+		{"not implemented", fake.Message(lib.MessageDamage, uint8(0), uint32(1000)), false, "message damage: decoder not implemented"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ocgcore.ReadMessage(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %q", err, tt.err)
+			}
+			if (msg != nil) != tt.ok {
+				t.Fatalf("got message %v", msg)
+			}
+		})
+	}
+}
+
+func TestReadMessageNotImplemented(t *testing.T) {
+	_, err := ocgcore.ReadMessage(fake.Message(lib.MessageDamage, uint8(0), uint32(1000)))
+	if !errors.Is(err, ocgcore.ErrNotImplemented) {
+		t.Fatalf("got error %v, want ErrNotImplemented", err)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	data := fake.Message(lib.MessageDraw, uint8(1), uint32(2), uint32(89631139), uint32(lib.PositionFaceUpAttack), uint32(46986414), uint32(lib.PositionFaceDownDefense))
+	msg, err := ocgcore.ReadMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draw, ok := msg.(ocgcore.MessageDraw)
+	if !ok {
+		t.Fatalf("got %T", msg)
+	}
+	if draw.Player != 1 || len(draw.Cards) != 2 || draw.Cards[0].Code != 89631139 || draw.Cards[1].Code != 46986414 {
+		t.Fatalf("got %+v", draw)
+	}
+}
+
+// The seeds in testdata/fuzz are built by hand from the decoders, more are
+// captured from real duels with cmd/bench -corpus.
+func FuzzReadMessage(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := ocgcore.ReadMessage(data)
+		if msg == nil && err == nil {
+			t.Fatal("no message and no error")
+		}
+	})
+}
+
+// readCorpus returns the seeds of a fuzz test.
+func readCorpus(tb testing.TB, name string) [][]byte {
+	files, err := filepath.Glob(filepath.Join("testdata", "fuzz", name, "*"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var corpus [][]byte
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		// go test fuzz v1
+		// []byte("...")
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		value := strings.TrimSuffix(strings.TrimPrefix(lines[len(lines)-1], "[]byte("), ")")
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			tb.Fatalf("%s: %v", f, err)
+		}
+		corpus = append(corpus, []byte(s))
+	}
+	return corpus
+}
+
+// BenchmarkReadMessage decodes the seed corpus of FuzzReadMessage, an
+// operation is a message.
+func BenchmarkReadMessage(b *testing.B) {
+	corpus := readCorpus(b, "FuzzReadMessage")
+	if len(corpus) == 0 {
+		b.Skip("no seeds")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ocgcore.ReadMessage(corpus[i%len(corpus)])
+	}
+}
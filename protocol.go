@@ -0,0 +1,123 @@
+package ocgcore
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProtocolVersion is a version of the core API. Message layouts change with
+// the major version, minor versions may add messages or fields.
+type ProtocolVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+}
+
+func (v ProtocolVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+func (v ProtocolVersion) less(o ProtocolVersion) bool {
+	return v.Major < o.Major || (v.Major == o.Major && v.Minor < o.Minor)
+}
+
+// MessageProtocol is the core API version the ReadMessage decoders and the
+// query parsers are written for.
+var MessageProtocol = ProtocolVersion{Major: 7, Minor: 0}
+
+// messageProtocols is the core API version each ReadMessage decoder is
+// written for. A decoder updated for a new layout must update its version,
+// the messages that aren't decoded yet are missing.
+var messageProtocols = map[MessageType]ProtocolVersion{
+	MessageTypeRetry:              MessageProtocol,
+	MessageTypeHint:               MessageProtocol,
+	MessageTypeWaiting:            MessageProtocol,
+	MessageTypeStart:              MessageProtocol,
+	MessageTypeWin:                MessageProtocol,
+	MessageTypeUpdateData:         MessageProtocol,
+	MessageTypeUpdateCard:         MessageProtocol,
+	MessageTypeRequestDeck:        MessageProtocol,
+	MessageTypeSelectBattleCMD:    MessageProtocol,
+	MessageTypeSelectIdleCMD:      MessageProtocol,
+	MessageTypeSelectEffectYN:     MessageProtocol,
+	MessageTypeSelectYesNo:        MessageProtocol,
+	MessageTypeSelectOption:       MessageProtocol,
+	MessageTypeSelectCard:         MessageProtocol,
+	MessageTypeSelectChain:        MessageProtocol,
+	MessageTypeSelectPlace:        MessageProtocol,
+	MessageTypeSelectPosition:     MessageProtocol,
+	MessageTypeSelectTribute:      MessageProtocol,
+	MessageTypeSortChain:          MessageProtocol,
+	MessageTypeSelectCounter:      MessageProtocol,
+	MessageTypeSelectSum:          MessageProtocol,
+	MessageTypeSelectDisfield:     MessageProtocol,
+	MessageTypeSortCard:           MessageProtocol,
+	MessageTypeSelectUnselectCard: MessageProtocol,
+	MessageTypeConfirmDeckTop:     MessageProtocol,
+	MessageTypeConfirmCards:       MessageProtocol,
+	MessageTypeShuffleDeck:        MessageProtocol,
+	MessageTypeNewTurn:            MessageProtocol,
+	MessageTypeNewPhase:           MessageProtocol,
+	MessageTypeMove:               MessageProtocol,
+	MessageTypeSummoning:          MessageProtocol,
+	MessageTypeSummoned:           MessageProtocol,
+	MessageTypeSPSummoning:        MessageProtocol,
+	MessageTypeSPSummoned:         MessageProtocol,
+	MessageTypeChaining:           MessageProtocol,
+	MessageTypeChained:            MessageProtocol,
+	MessageTypeChainSolving:       MessageProtocol,
+	MessageTypeChainSolved:        MessageProtocol,
+	MessageTypeChainEnd:           MessageProtocol,
+	MessageTypeBecomeTarget:       MessageProtocol,
+	MessageTypeDraw:               MessageProtocol,
+	MessageTypeTagSwap:            MessageProtocol,
+	MessageTypeReloadField:        MessageProtocol,
+	MessageTypeAIName:             MessageProtocol,
+	MessageTypeShowHint:           MessageProtocol,
+}
+
+// MessageProtocolVersion returns the core API version the decoder of a
+// message targets, false if the message isn't decoded.
+func MessageProtocolVersion(t MessageType) (ProtocolVersion, bool) {
+	v, ok := messageProtocols[t]
+	return v, ok
+}
+
+// CoreVersion returns the API version of the core run by a backend, or by the
+// default one when nil, loading it if needed.
+func CoreVersion(backend Backend) (ProtocolVersion, error) {
+	backend, err := backendOrDefault(backend)
+	if err != nil {
+		return ProtocolVersion{}, err
+	}
+	return backend.Version()
+}
+
+// CheckCoreVersion compares the version of the core with the one of the
+// decoders. It fails when the major versions differ or the core is older than
+// a decoder. A newer minor version only gives a warning, its messages may
+// have fields the decoders don't know about.
+func CheckCoreVersion(backend Backend) (warnings []string, err error) {
+	core, err := CoreVersion(backend)
+	if err != nil {
+		return nil, err
+	}
+	if core.Major != MessageProtocol.Major {
+		return nil, fmt.Errorf("core API version %s is not supported, the message decoders are written for %s", core, MessageProtocol)
+	}
+
+	var types []MessageType
+	for t := range messageProtocols {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, t := range types {
+		if v := messageProtocols[t]; core.less(v) {
+			return nil, fmt.Errorf("core API version %s is older than %s, required to decode %s", core, v, t)
+		}
+	}
+
+	if MessageProtocol.less(core) {
+		warnings = append(warnings, fmt.Sprintf("core API version %s is newer than the message decoders, written for %s", core, MessageProtocol))
+	}
+	return warnings, nil
+}
@@ -0,0 +1,51 @@
+package ocgcore
+
+import "fmt"
+
+// LoadPuzzle creates a duel in test mode and runs a puzzle script against it.
+// Puzzle scripts set up the field through the Debug library and end with
+// Debug.ReloadFieldEnd, whose field snapshot is returned. Any other message
+// produced while loading is delivered once the duel is started.
+func LoadPuzzle(options CreateDuelOptions, name string, contents []byte) (*OcgDuel, MessageReloadField, error) {
+	if len(contents) == 0 {
+		return nil, MessageReloadField{}, fmt.Errorf("puzzle %s: empty script", name)
+	}
+
+	options.TestMode = true
+	duel, err := CreateDuel(options)
+	if err != nil {
+		return nil, MessageReloadField{}, err
+	}
+
+	if !duel.backend.LoadScript(duel.handle, contents, name) {
+		duel.backend.DestroyDuel(duel.handle)
+		if r := duel.Diagnostics(); len(r.Errors) > 0 {
+			return nil, MessageReloadField{}, fmt.Errorf("puzzle %s: script failed: %s", name, r.Errors[len(r.Errors)-1].Message)
+		}
+		return nil, MessageReloadField{}, fmt.Errorf("puzzle %s: script failed", name)
+	}
+
+	messages, err := duelGetMessage(duel.backend, duel.handle)
+	if err != nil {
+		duel.diagnostics.addLog(LogTypeError, fmt.Sprintf("reading messages: %v", err))
+	}
+	var field *MessageReloadField
+	for _, message := range messages {
+		msg, err := readMessage(message)
+		if err != nil {
+			duel.diagnostics.addLog(LogTypeError, err.Error())
+		}
+		if m, ok := msg.(MessageReloadField); ok {
+			field = &m
+			continue
+		}
+		if msg != nil {
+			duel.pending = append(duel.pending, msg)
+		}
+	}
+	if field == nil {
+		duel.backend.DestroyDuel(duel.handle)
+		return nil, MessageReloadField{}, fmt.Errorf("puzzle %s: missing Debug.ReloadFieldEnd", name)
+	}
+	return duel, *field, nil
+}
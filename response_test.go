@@ -0,0 +1,12 @@
+package ocgcore
+
+import "testing"
+
+func FuzzJSONToResponse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := JSONToResponse(data)
+		if err == nil && r != nil {
+			_ = r.responseWrite()
+		}
+	})
+}
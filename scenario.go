@@ -0,0 +1,277 @@
+package ocgcore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"ocgcore/lib"
+	"os"
+	"strings"
+)
+
+// Scenario describes a custom starting state for a duel, used for puzzles and
+// combo training. Players are numbered from the point of view of the
+// scenario: the core always gives the first turn to team 0, so TurnPlayer
+// decides which scenario player is seated as team 0.
+type Scenario struct {
+	LP         [2]int         `json:"lp"`
+	TurnPlayer int            `json:"turn_player"`
+	Cards      []ScenarioCard `json:"cards"`
+}
+
+type ScenarioCard struct {
+	Code     uint32   `json:"code"`
+	Player   int      `json:"player"`
+	Location Location `json:"location"`
+	// Sequence is the zone in the monster and spell & trap zones. Formats
+	// with 3 columns only use the zones 1 to 3, the extra monster zones are
+	// 5 and 6.
+	Sequence int      `json:"sequence,omitempty"`
+	Position Position `json:"position,omitempty"`
+	Overlay  []uint32 `json:"overlay,omitempty"`
+}
+
+// Team returns the core team a scenario player is seated as.
+func (s Scenario) Team(player int) int {
+	return player ^ s.TurnPlayer
+}
+
+func LoadScenario(r io.Reader) (s Scenario, err error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&s)
+	return
+}
+
+func LoadScenarioFile(fileName string) (Scenario, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return Scenario{}, err
+	}
+	defer f.Close()
+	return LoadScenario(f)
+}
+
+type ScenarioBuilder struct {
+	duel     *OcgDuel
+	scenario Scenario
+}
+
+// Scenario returns a builder that places cards directly on the field. It must
+// be applied before the duel is started, and replaces the opening draw: hands
+// are placed explicitly.
+func (d *OcgDuel) Scenario() *ScenarioBuilder {
+	return &ScenarioBuilder{duel: d}
+}
+
+// Load merges a scenario into the builder. Non-zero LP values and the turn
+// player override the ones already set, cards are appended.
+func (b *ScenarioBuilder) Load(s Scenario) *ScenarioBuilder {
+	for i, lp := range s.LP {
+		if lp != 0 {
+			b.scenario.LP[i] = lp
+		}
+	}
+	b.scenario.TurnPlayer = s.TurnPlayer
+	b.scenario.Cards = append(b.scenario.Cards, s.Cards...)
+	return b
+}
+
+func (b *ScenarioBuilder) LP(player int, lp int) *ScenarioBuilder {
+	b.scenario.LP[player] = lp
+	return b
+}
+
+func (b *ScenarioBuilder) TurnPlayer(player int) *ScenarioBuilder {
+	b.scenario.TurnPlayer = player
+	return b
+}
+
+func (b *ScenarioBuilder) Card(card ScenarioCard) *ScenarioBuilder {
+	b.scenario.Cards = append(b.scenario.Cards, card)
+	return b
+}
+
+func (b *ScenarioBuilder) cards(player int, location Location, codes []uint32) *ScenarioBuilder {
+	for _, code := range codes {
+		b.Card(ScenarioCard{Code: code, Player: player, Location: location})
+	}
+	return b
+}
+
+func (b *ScenarioBuilder) Deck(player int, codes ...uint32) *ScenarioBuilder {
+	return b.cards(player, LocationDeck, codes)
+}
+
+func (b *ScenarioBuilder) ExtraDeck(player int, codes ...uint32) *ScenarioBuilder {
+	return b.cards(player, LocationExtraDeck, codes)
+}
+
+func (b *ScenarioBuilder) Hand(player int, codes ...uint32) *ScenarioBuilder {
+	return b.cards(player, LocationHand, codes)
+}
+
+func (b *ScenarioBuilder) Grave(player int, codes ...uint32) *ScenarioBuilder {
+	return b.cards(player, LocationGrave, codes)
+}
+
+func (b *ScenarioBuilder) Banished(player int, codes ...uint32) *ScenarioBuilder {
+	return b.cards(player, LocationBanished, codes)
+}
+
+func (b *ScenarioBuilder) Monster(player int, sequence int, code uint32, position Position, overlay ...uint32) *ScenarioBuilder {
+	return b.Card(ScenarioCard{
+		Code:     code,
+		Player:   player,
+		Location: LocationMonsterZone,
+		Sequence: sequence,
+		Position: position,
+		Overlay:  overlay,
+	})
+}
+
+func (b *ScenarioBuilder) Spell(player int, sequence int, code uint32, position Position) *ScenarioBuilder {
+	return b.Card(ScenarioCard{
+		Code:     code,
+		Player:   player,
+		Location: LocationSpellZone,
+		Sequence: sequence,
+		Position: position,
+	})
+}
+
+func (b *ScenarioBuilder) FieldSpell(player int, code uint32, position Position) *ScenarioBuilder {
+	return b.Card(ScenarioCard{
+		Code:     code,
+		Player:   player,
+		Location: LocationFieldZone,
+		Position: position,
+	})
+}
+
+func (b *ScenarioBuilder) Pendulum(player int, sequence int, code uint32) *ScenarioBuilder {
+	return b.Card(ScenarioCard{
+		Code:     code,
+		Player:   player,
+		Location: LocationPendulumZone,
+		Sequence: sequence,
+		Position: PositionFaceUpAttack,
+	})
+}
+
+// Apply places every card and applies the LP overrides. Overlay materials
+// and player info are set through a generated Debug script, as the core
+// only attaches materials from there.
+func (b *ScenarioBuilder) Apply() error {
+	s := b.scenario
+	if s.TurnPlayer != 0 && s.TurnPlayer != 1 {
+		return fmt.Errorf("invalid turn player: %d", s.TurnPlayer)
+	}
+
+	var script strings.Builder
+	for player := 0; player < 2; player++ {
+		team := s.Team(player)
+		lp := s.LP[player]
+		if lp == 0 {
+			lp = int(b.duel.teams[team].StartingLP)
+		}
+		if lp < 0 {
+			return fmt.Errorf("invalid lp for player %d: %d", player, lp)
+		}
+		_, _ = fmt.Fprintf(&script, "Debug.SetPlayerInfo(%d,%d,0,%d)\n", team, lp, b.duel.teams[team].DrawCountPerTurn)
+	}
+
+	for _, card := range s.Cards {
+		info, err := s.newCardInfo(card, b.duel.format)
+		if err != nil {
+			return err
+		}
+		if len(card.Overlay) > 0 && card.Location != LocationMonsterZone {
+			return fmt.Errorf("card %d: overlay materials outside of the monster zone", card.Code)
+		}
+		b.duel.backend.NewCard(b.duel.handle, info)
+
+		for _, code := range card.Overlay {
+			_, _ = fmt.Fprintf(&script, "Debug.AddCard(%d,%d,%d,%d,%d,%d)\n",
+				code, info.Team, info.Controller, lib.LocationOverlay, info.Sequence, lib.PositionFaceUp)
+		}
+	}
+
+	if !b.duel.backend.LoadScript(b.duel.handle, []byte(script.String()), "scenario.lua") {
+		return errors.New("scenario script failed")
+	}
+	return nil
+}
+
+// inColumns reports whether a main monster or spell & trap zone is used by
+// the format.
+func inColumns(format Format, sequence int) bool {
+	for _, seq := range format.columnSequences() {
+		if seq == sequence {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scenario) newCardInfo(card ScenarioCard, format Format) (info lib.NewCardInfo, err error) {
+	if card.Player != 0 && card.Player != 1 {
+		err = fmt.Errorf("card %d: invalid player %d", card.Code, card.Player)
+		return
+	}
+	team := uint8(s.Team(card.Player))
+
+	info.Team = team
+	info.Controller = team
+	info.Code = card.Code
+	info.Location = convertLocation(card.Location)
+
+	position := card.Position
+	switch card.Location {
+	case LocationDeck, LocationExtraDeck, LocationHand:
+		if position == PositionUnknown {
+			position = PositionFaceDownDefense
+		}
+	case LocationGrave, LocationBanished:
+		if position == PositionUnknown {
+			position = PositionFaceUpAttack
+		}
+	case LocationMonsterZone:
+		extra := format.ExtraMonsterZones() && (card.Sequence == 5 || card.Sequence == 6)
+		if !inColumns(format, card.Sequence) && !extra {
+			err = fmt.Errorf("card %d: invalid monster zone %d", card.Code, card.Sequence)
+			return
+		}
+		info.Sequence = uint32(card.Sequence)
+		if position == PositionUnknown {
+			position = PositionFaceUpAttack
+		}
+	case LocationSpellZone:
+		if !inColumns(format, card.Sequence) {
+			err = fmt.Errorf("card %d: invalid spell zone %d", card.Code, card.Sequence)
+			return
+		}
+		info.Sequence = uint32(card.Sequence)
+		if position == PositionUnknown {
+			position = PositionFaceUpAttack
+		}
+	case LocationFieldZone:
+		if position == PositionUnknown {
+			position = PositionFaceUpAttack
+		}
+	case LocationPendulumZone:
+		if card.Sequence < 0 || card.Sequence > 1 {
+			err = fmt.Errorf("card %d: invalid pendulum zone %d", card.Code, card.Sequence)
+			return
+		}
+		info.Sequence = uint32(card.Sequence)
+		position = PositionFaceUpAttack
+	default:
+		err = fmt.Errorf("card %d: invalid location %v", card.Code, card.Location)
+		return
+	}
+
+	info.Position = convertPosition(position)
+	return
+}
@@ -0,0 +1,63 @@
+package ocgcore_test
+
+import (
+	"fmt"
+	"ocgcore"
+	"ocgcore/fake"
+	"ocgcore/lib"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScenarioApply(t *testing.T) {
+	// player 1 goes first, so it is seated as team 0
+	scenario, err := ocgcore.LoadScenario(strings.NewReader(`{
+		"lp": [4000, 0],
+		"turn_player": 1,
+		"cards": [
+			{"code": 100, "player": 0, "location": "hand"},
+			{"code": 200, "player": 1, "location": "monster_zone", "sequence": 2, "position": "face_up_defense", "overlay": [300, 400]},
+			{"code": 500, "player": 0, "location": "spell_zone", "sequence": 1, "position": "face_down_defense"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := fake.New()
+	duel, err := ocgcore.CreateDuel(ocgcore.CreateDuelOptions{
+		Mode:         ocgcore.DuelModeMR5,
+		Backend:      backend,
+		CardReader:   fake.CardReader,
+		ScriptReader: fake.ScriptReader,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := duel.Scenario().Load(scenario).Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := backend.Duels()[0]
+	cards := []lib.NewCardInfo{
+		{Team: 1, Controller: 1, Code: 100, Location: lib.LocationHand, Position: lib.PositionFaceDownDefense},
+		{Team: 0, Controller: 0, Code: 200, Location: lib.LocationMZone, Sequence: 2, Position: lib.PositionFaceUpDefense},
+		{Team: 1, Controller: 1, Code: 500, Location: lib.LocationSZone, Sequence: 1, Position: lib.PositionFaceDownDefense},
+	}
+	if !reflect.DeepEqual(d.Cards, cards) {
+		t.Errorf("got cards %+v, want %+v", d.Cards, cards)
+	}
+
+	last := len(d.Scripts) - 1
+	if last < 0 || d.Scripts[last] != "scenario.lua" {
+		t.Fatalf("scenario script not loaded, scripts %v", d.Scripts)
+	}
+	script := "Debug.SetPlayerInfo(1,4000,0,1)\n" +
+		"Debug.SetPlayerInfo(0,8000,0,1)\n" +
+		fmt.Sprintf("Debug.AddCard(300,0,0,%d,2,%d)\n", lib.LocationOverlay, lib.PositionFaceUp) +
+		fmt.Sprintf("Debug.AddCard(400,0,0,%d,2,%d)\n", lib.LocationOverlay, lib.PositionFaceUp)
+	if got := string(d.Sources[last]); got != script {
+		t.Errorf("got script\n%s\nwant\n%s", got, script)
+	}
+}
@@ -0,0 +1,77 @@
+package script
+
+import (
+	"container/list"
+	"ocgcore"
+	"sync"
+)
+
+type cacheEntry struct {
+	name     string
+	contents []byte
+}
+
+// CacheReader is a script reader keeping the scripts in memory, see Cache.
+type CacheReader struct {
+	reader   ocgcore.ScriptReader
+	maxBytes int
+
+	lock    sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// Cache keeps the scripts read from r in memory, up to maxBytes. The least
+// recently used scripts are evicted first, missing scripts aren't cached.
+// Edited scripts are read again only after Purge.
+func Cache(r ocgcore.ScriptReader, maxBytes int) *CacheReader {
+	return &CacheReader{
+		reader:   r,
+		maxBytes: maxBytes,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// Purge drops the cached scripts.
+func (c *CacheReader) Purge() {
+	c.lock.Lock()
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+	c.size = 0
+	c.lock.Unlock()
+}
+
+func (c *CacheReader) Read(name string) []byte {
+	c.lock.Lock()
+	if e, ok := c.entries[name]; ok {
+		c.lru.MoveToFront(e)
+		contents := e.Value.(*cacheEntry).contents
+		c.lock.Unlock()
+		return contents
+	}
+	c.lock.Unlock()
+
+	contents := c.reader(name)
+	if len(contents) == 0 || len(contents) > c.maxBytes {
+		return contents
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.entries[name]; ok {
+		return contents
+	}
+	c.entries[name] = c.lru.PushFront(&cacheEntry{name: name, contents: contents})
+	c.size += len(contents)
+	for c.size > c.maxBytes {
+		e := c.lru.Back()
+		entry := e.Value.(*cacheEntry)
+		c.lru.Remove(e)
+		delete(c.entries, entry.name)
+		c.size -= len(entry.contents)
+	}
+	return contents
+}
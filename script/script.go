@@ -0,0 +1,65 @@
+package script
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"ocgcore"
+	"os"
+	"path"
+	"regexp"
+)
+
+var cardScriptRegex = regexp.MustCompile(`^c\d+\.lua$`)
+
+// FS reads scripts from a filesystem laid out like the script repository,
+// with the card scripts in official/. Card scripts at the root are found too,
+// which is common for custom cards.
+func FS(fsys fs.FS) ocgcore.ScriptReader {
+	return func(name string) []byte {
+		name = path.Clean(name)
+		if !fs.ValidPath(name) {
+			return nil
+		}
+
+		candidates := []string{name}
+		if cardScriptRegex.MatchString(name) {
+			candidates = append(candidates, path.Join("official", name))
+		}
+		for _, candidate := range candidates {
+			contents, err := fs.ReadFile(fsys, candidate)
+			if err == nil {
+				return contents
+			}
+		}
+		return nil
+	}
+}
+
+// Dir reads scripts from a directory.
+func Dir(dir string) ocgcore.ScriptReader {
+	return FS(os.DirFS(dir))
+}
+
+// OpenZip reads scripts from a zip archive, which stays open until the
+// returned closer is closed.
+func OpenZip(fileName string) (ocgcore.ScriptReader, io.Closer, error) {
+	r, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return FS(r), r, nil
+}
+
+// Overlay reads each script from the first reader that has it, so that the
+// earlier readers shadow the later ones.
+func Overlay(readers ...ocgcore.ScriptReader) ocgcore.ScriptReader {
+	return func(name string) []byte {
+		for _, r := range readers {
+			if contents := r(name); len(contents) > 0 {
+				return contents
+			}
+		}
+		return nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/gorilla/websocket"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -11,6 +12,21 @@ type Client struct {
 	server *Server
 	conn   *websocket.Conn
 	send   chan []byte
+
+	localeLock sync.Mutex
+	locale     string
+}
+
+func (c *Client) Locale() string {
+	c.localeLock.Lock()
+	defer c.localeLock.Unlock()
+	return c.locale
+}
+
+func (c *Client) setLocale(locale string) {
+	c.localeLock.Lock()
+	defer c.localeLock.Unlock()
+	c.locale = locale
 }
 
 func (c *Client) readPump() {
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"ocgcore"
+	"sync"
+)
+
+const maxTeamSize = 3
+
+var errNotYourTurn = errors.New("not your turn to respond")
+
+// duelInfo is a duel with the clients seated in it. In tag and relay duels
+// each team has more than one seat, and only the active duelist of the team
+// that is being prompted can answer.
+type duelInfo struct {
+	id       int
+	duel     *ocgcore.OcgDuel
+	done     chan bool
+	teamSize [2]int
+	relay    bool
+	format   ocgcore.Format
+
+	lock    sync.Mutex
+	seats   [2][]*Client
+	decks   [2][]*messageDeck
+	current [2]int
+	// waiting is the team of the last prompt, its duelist stays the
+	// responder until the next prompt in case the core asks again
+	waiting   int
+	responded bool
+
+	closeOnce sync.Once
+}
+
+func newDuelInfo(id int, owner *Client, m messageCreateDuel) (*duelInfo, error) {
+	d := &duelInfo{
+		id:      id,
+		relay:   m.Relay,
+		format:  ocgcore.FormatMasterRule,
+		waiting: -1,
+	}
+	if m.Format != "" {
+		format, ok := ocgcore.FormatByName(m.Format)
+		if !ok {
+			return nil, errors.New("unknown format")
+		}
+		d.format = format
+	}
+	for team, size := range m.TeamSize {
+		if size == 0 {
+			size = 1
+		}
+		if size < 0 || size > maxTeamSize {
+			return nil, errors.New("invalid team size")
+		}
+		d.teamSize[team] = size
+		d.seats[team] = make([]*Client, size)
+		d.decks[team] = make([]*messageDeck, size)
+	}
+	d.seats[0][0] = owner
+	return d, nil
+}
+
+func (d *duelInfo) join(c *Client, team int, slot int) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.duel != nil {
+		return errors.New("duel already started")
+	}
+	if team < 0 || team > 1 || slot < 0 || slot >= d.teamSize[team] {
+		return errors.New("invalid seat")
+	}
+	if d.seats[team][slot] != nil {
+		return errors.New("seat already taken")
+	}
+	d.seats[team][slot] = c
+	return nil
+}
+
+func (d *duelInfo) setDeck(c *Client, deck *messageDeck, cards func(code uint32) (ocgcore.DeckCard, bool)) error {
+	if err := d.format.ValidateDeck(deck.Main, deck.Extra, cards); err != nil {
+		return err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.duel != nil {
+		return errors.New("duel already started")
+	}
+	team, slot, ok := d.seat(c)
+	if !ok {
+		return errors.New("not seated in the duel")
+	}
+	d.decks[team][slot] = deck
+	return nil
+}
+
+func (d *duelInfo) seat(c *Client) (int, int, bool) {
+	for team, seats := range d.seats {
+		for slot, seated := range seats {
+			if seated == c {
+				return team, slot, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func (d *duelInfo) clients() []*Client {
+	var clients []*Client
+	for _, seats := range d.seats {
+		for _, c := range seats {
+			if c != nil {
+				clients = append(clients, c)
+			}
+		}
+	}
+	return clients
+}
+
+// recipients tracks the seat rotation and returns the clients a message has
+// to be sent to. Prompts only go to the duelist that has to answer them.
+func (d *duelInfo) recipients(m ocgcore.Message) []*Client {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	switch m := m.(type) {
+	case ocgcore.MessageTagSwap:
+		d.current[m.Player] = (d.current[m.Player] + 1) % d.teamSize[m.Player]
+	case ocgcore.MessageRetry:
+		if d.waiting >= 0 {
+			d.responded = false
+			return []*Client{d.seats[d.waiting][d.current[d.waiting]]}
+		}
+	case ocgcore.MessageWaitingResponse:
+		if d.waiting >= 0 {
+			return []*Client{d.seats[d.waiting][d.current[d.waiting]]}
+		}
+	}
+	if player, ok := ocgcore.ResponsePlayer(m); ok {
+		d.waiting = player
+		d.responded = false
+		return []*Client{d.seats[player][d.current[player]]}
+	}
+	return d.clients()
+}
+
+func (d *duelInfo) checkResponder(c *Client) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.waiting < 0 || d.responded || d.seats[d.waiting][d.current[d.waiting]] != c {
+		return errNotYourTurn
+	}
+	d.responded = true
+	return nil
+}
+
+func (s *Server) createDuel(c *Client, payload json.RawMessage) (*duelInfo, error) {
+	var m messageCreateDuel
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil, err
+		}
+	}
+
+	s.duelsLock.Lock()
+	defer s.duelsLock.Unlock()
+
+	if _, ok := s.duels[c]; ok {
+		return nil, errors.New("already in a duel")
+	}
+	s.lastDuelId++
+	duel, err := newDuelInfo(s.lastDuelId, c, m)
+	if err != nil {
+		return nil, err
+	}
+	s.rooms[duel.id] = duel
+	s.duels[c] = duel
+	return duel, nil
+}
+
+func (s *Server) joinDuel(c *Client, m messageJoinDuel) error {
+	s.duelsLock.Lock()
+	defer s.duelsLock.Unlock()
+
+	if _, ok := s.duels[c]; ok {
+		return errors.New("already in a duel")
+	}
+	duel, ok := s.rooms[m.Duel]
+	if !ok {
+		return errors.New("duel not found")
+	}
+	if err := duel.join(c, m.Team, m.Slot); err != nil {
+		return err
+	}
+	s.duels[c] = duel
+	return nil
+}
+
+func (s *Server) getDuel(c *Client) (*duelInfo, error) {
+	s.duelsLock.Lock()
+	defer s.duelsLock.Unlock()
+
+	duel, ok := s.duels[c]
+	if !ok {
+		return nil, errors.New("first create the duel")
+	}
+	return duel, nil
+}
+
+func (s *Server) startDuel(c *Client) error {
+	duel, err := s.getDuel(c)
+	if err != nil {
+		return err
+	}
+
+	duel.lock.Lock()
+	if duel.duel != nil {
+		duel.lock.Unlock()
+		return errors.New("duel already started")
+	}
+	for team := range duel.seats {
+		for slot := range duel.seats[team] {
+			if duel.seats[team][slot] == nil || duel.decks[team][slot] == nil {
+				duel.lock.Unlock()
+				return errors.New("every seat needs a duelist and a deck")
+			}
+		}
+	}
+
+	d, err := ocgcore.CreateDuel(ocgcore.CreateDuelOptions{
+		Seed:         0,
+		Format:       &duel.format,
+		TeamSize:     duel.teamSize,
+		Relay:        duel.relay,
+		CardReader:   s.cardDatabase().Reader(),
+		ScriptReader: s.config.ScriptReader,
+		Backend:      s.config.Backend,
+		LogHandler:   s.logHandler("duel", duel.id),
+	})
+	if err != nil {
+		duel.lock.Unlock()
+		log.Printf("duel %d: %v", duel.id, err)
+		return err
+	}
+	duel.duel = d
+	for team := range duel.decks {
+		for slot, deck := range duel.decks[team] {
+			duel.duel.SetupDuelistDeck(team, slot, deck.Main, deck.Extra, true)
+		}
+	}
+	duel.done = make(chan bool)
+	messages := duel.duel.Start()
+	duel.lock.Unlock()
+
+	go func() {
+	outer:
+		for {
+			select {
+			case m1, ok := <-messages:
+				if !ok {
+					break outer
+				}
+
+				m2, _ := ocgcore.MessageToJSON(m1)
+				for _, c := range duel.recipients(m1) {
+					err := s.sendClientRaw(c, "message", m2)
+					text := ocgcore.MessageText(m1, s.describer(c.Locale()))
+					if err == nil && text != "" {
+						err = s.sendClient(c, "message_text", resultMessageText{Text: text})
+					}
+					if err != nil {
+						s.kickClient(c, err)
+						break outer
+					}
+				}
+			case _, _ = <-duel.done:
+				break outer
+			}
+		}
+		_ = s.destroyDuel(c)
+	}()
+	return nil
+}
+
+func (s *Server) duelDiagnostics(c *Client) (ocgcore.Diagnostics, error) {
+	duel, err := s.getDuel(c)
+	if err != nil {
+		return ocgcore.Diagnostics{}, err
+	}
+
+	duel.lock.Lock()
+	defer duel.lock.Unlock()
+	if duel.duel == nil {
+		return ocgcore.Diagnostics{}, errors.New("duel not started")
+	}
+	return duel.duel.Diagnostics(), nil
+}
+
+func (s *Server) destroyDuel(c *Client) error {
+	s.duelsLock.Lock()
+	duel, ok := s.duels[c]
+	if !ok {
+		s.duelsLock.Unlock()
+		return errors.New("first create the duel")
+	}
+	for _, seated := range duel.clients() {
+		delete(s.duels, seated)
+	}
+	delete(s.rooms, duel.id)
+	s.duelsLock.Unlock()
+
+	duel.closeOnce.Do(func() {
+		duel.lock.Lock()
+		defer duel.lock.Unlock()
+		if duel.duel != nil {
+			close(duel.done)
+			duel.duel.Destroy()
+		}
+	})
+	return nil
+}
+
+func (s *Server) duelResponse(c *Client, payload json.RawMessage) error {
+	duel, err := s.getDuel(c)
+	if err != nil {
+		return err
+	}
+	if err := duel.checkResponder(c); err != nil {
+		return err
+	}
+	resp, err := ocgcore.JSONToResponse(payload)
+	if err != nil {
+		return err
+	}
+	duel.duel.SendResponse(resp)
+	return nil
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"ocgcore"
+	"ocgcore/fake"
+	"ocgcore/lib"
+	"testing"
+	"time"
+)
+
+// event is what a client received, the text of message_text or the action.
+type event string
+
+func receive(t *testing.T, c *Client) event {
+	t.Helper()
+	select {
+	case data := <-c.send:
+		var m jsonMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		if m.Action != "message_text" {
+			return event(m.Action)
+		}
+		var text resultMessageText
+		if err := json.Unmarshal(m.Payload, &text); err != nil {
+			t.Fatal(err)
+		}
+		return event(text.Text)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return ""
+	}
+}
+
+func expect(t *testing.T, c *Client, events ...event) {
+	t.Helper()
+	for _, want := range events {
+		if got := receive(t, c); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDuelPromptRouting(t *testing.T) {
+	tagSwap := fake.Message(lib.MessageTagSwap, uint8(0), uint32(0), uint32(0), uint32(0), uint32(0), uint32(0))
+	backend := fake.New(
+		fake.Step{Messages: [][]byte{fake.Message(lib.MessageSelectYesNo, uint8(0), uint64(30))}, Wait: true},
+		fake.Step{Messages: [][]byte{fake.Message(lib.MessageRetry)}, Wait: true},
+		fake.Step{Messages: [][]byte{tagSwap, fake.Message(lib.MessageSelectYesNo, uint8(0), uint64(31))}, Wait: true},
+		fake.Step{Messages: [][]byte{fake.Message(lib.MessageSelectYesNo, uint8(1), uint64(32))}, Wait: true},
+		fake.Step{Messages: [][]byte{fake.Message(lib.MessageWin, uint8(1), uint8(0))}},
+	)
+	s := NewServer(Config{Backend: backend, ScriptReader: fake.ScriptReader})
+
+	var seats [2][2]*Client
+	for team := range seats {
+		for slot := range seats[team] {
+			seats[team][slot] = &Client{server: s, send: make(chan []byte, 16)}
+		}
+	}
+	owner := seats[0][0]
+	duel, err := s.createDuel(owner, json.RawMessage(`{"team_size":[2,2]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for team := range seats {
+		for slot, c := range seats[team] {
+			if c != owner {
+				if err := s.joinDuel(c, messageJoinDuel{Duel: duel.id, Team: team, Slot: slot}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			duel.decks[team][slot] = &messageDeck{Main: []uint32{uint32(1000*(team+1) + slot)}}
+		}
+	}
+	if err := s.startDuel(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	// answer checks that the events of a prompt reached only c, that no one
+	// else can answer it, then answers it.
+	answer := func(c *Client, events ...event) {
+		t.Helper()
+		expect(t, c, events...)
+		for _, team := range seats {
+			for _, other := range team {
+				if other == c {
+					continue
+				}
+				if len(other.send) != 0 {
+					t.Fatalf("prompt %q sent to another duelist", events)
+				}
+				if err := duel.checkResponder(other); err != errNotYourTurn {
+					t.Fatalf("another duelist answered %q: %v", events, err)
+				}
+			}
+		}
+		if err := duel.checkResponder(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := duel.checkResponder(c); err != errNotYourTurn {
+			t.Fatalf("prompt %q answered twice: %v", events, err)
+		}
+		duel.duel.SendResponse(ocgcore.ResponseSelectYesNo{Yes: true})
+	}
+
+	answer(seats[0][0], "message", "Player 1: string 30?", "message")
+	// the core asks the same duelist again after a rejected response
+	answer(seats[0][0], "message", "message")
+	for _, team := range seats {
+		for _, c := range team {
+			expect(t, c, "message", "Player 1 swaps duelist")
+		}
+	}
+	answer(seats[0][1], "message", "Player 1: string 31?", "message")
+	answer(seats[1][0], "message", "Player 2: string 32?", "message")
+	for _, team := range seats {
+		for _, c := range team {
+			expect(t, c, "message", "Player 2 wins")
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !backend.Duels()[0].Destroyed {
+		if time.Now().After(deadline) {
+			t.Fatal("duel not destroyed after the win")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.getDuel(owner); err == nil {
+		t.Error("duel still registered after the win")
+	}
+
+	d := backend.Duels()[0]
+	if len(d.Responses) != 4 {
+		t.Fatalf("%d responses, want 4", len(d.Responses))
+	}
+	for i, r := range d.Responses {
+		if !bytes.Equal(r, []byte{1, 0, 0, 0}) {
+			t.Errorf("response %d is %v", i, r)
+		}
+	}
+	if len(d.Cards) != 4 {
+		t.Fatalf("%d cards, want 4", len(d.Cards))
+	}
+	for _, card := range d.Cards {
+		team, slot := int(card.Team), int(card.Duelist)
+		if card.Code != uint32(1000*(team+1)+slot) {
+			t.Errorf("card %d in the deck of duelist %d of team %d", card.Code, slot, team)
+		}
+	}
+}
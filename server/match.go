@@ -0,0 +1,338 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"ocgcore"
+	"sync"
+)
+
+const maxSideDeckSize = 15
+
+// matchInfo is a best-of-N match between two clients. A game starts once the
+// chooser picked who goes first and both players confirmed their deck, side
+// decking it if they want.
+type matchInfo struct {
+	id     int
+	bestOf int
+	format ocgcore.Format
+
+	lock    sync.Mutex
+	players [2]*Client
+	decks   [2]ocgcore.Deck
+	match   *ocgcore.Match
+	duel    *ocgcore.OcgDuel
+	ready   [2]bool
+	chosen  bool
+	// waiting is the player of the last prompt, they stay the responder
+	// until the next prompt in case the core asks again
+	waiting   int
+	responded bool
+
+	closeOnce sync.Once
+}
+
+func (m *matchInfo) player(c *Client) (int, bool) {
+	for p, player := range m.players {
+		if player != nil && player == c {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func (m *matchInfo) clients() []*Client {
+	var clients []*Client
+	for _, c := range m.players {
+		if c != nil {
+			clients = append(clients, c)
+		}
+	}
+	return clients
+}
+
+func (m *matchInfo) started() *ocgcore.Match {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.match
+}
+
+func (s *Server) validateMatchDeck(format ocgcore.Format, deck ocgcore.Deck) error {
+	if err := format.ValidateDeck(deck.Main, deck.Extra, s.cardDatabase().DeckCard); err != nil {
+		return err
+	}
+	if len(deck.Side) > maxSideDeckSize {
+		return errors.New("side deck too big")
+	}
+	for _, code := range deck.Side {
+		if _, ok := s.cardDatabase().DeckCard(code); !ok {
+			return errors.New("side deck card not found")
+		}
+	}
+	return nil
+}
+
+func (s *Server) createMatch(c *Client, m messageCreateMatch) (*matchInfo, error) {
+	format := ocgcore.FormatMasterRule
+	if m.Format != "" {
+		var ok bool
+		if format, ok = ocgcore.FormatByName(m.Format); !ok {
+			return nil, errors.New("unknown format")
+		}
+	}
+	if err := s.validateMatchDeck(format, m.Deck); err != nil {
+		return nil, err
+	}
+
+	s.matchesLock.Lock()
+	defer s.matchesLock.Unlock()
+
+	if _, ok := s.matches[c]; ok {
+		return nil, errors.New("already in a match")
+	}
+	s.lastMatchId++
+	match := &matchInfo{
+		id:      s.lastMatchId,
+		bestOf:  m.BestOf,
+		format:  format,
+		waiting: -1,
+	}
+	match.players[0] = c
+	match.decks[0] = m.Deck
+	s.matchRooms[match.id] = match
+	s.matches[c] = match
+	return match, nil
+}
+
+func (s *Server) joinMatch(c *Client, m messageJoinMatch) error {
+	s.matchesLock.Lock()
+	defer s.matchesLock.Unlock()
+
+	if _, ok := s.matches[c]; ok {
+		return errors.New("already in a match")
+	}
+	match, ok := s.matchRooms[m.Match]
+	if !ok {
+		return errors.New("match not found")
+	}
+	if err := s.validateMatchDeck(match.format, m.Deck); err != nil {
+		return err
+	}
+
+	match.lock.Lock()
+	defer match.lock.Unlock()
+
+	if match.players[1] != nil {
+		return errors.New("match already full")
+	}
+	format := match.format
+	options := ocgcore.MatchOptions{
+		Duel: ocgcore.CreateDuelOptions{
+			Format:       &format,
+			CardReader:   s.cardDatabase().Reader(),
+			ScriptReader: s.config.ScriptReader,
+			Backend:      s.config.Backend,
+			LogHandler:   s.logHandler("match", match.id),
+		},
+		BestOf:  match.bestOf,
+		Decks:   [2]ocgcore.Deck{match.decks[0], m.Deck},
+		Chooser: rand.Intn(2),
+	}
+	om, err := ocgcore.NewMatch(options)
+	if err != nil {
+		return err
+	}
+
+	match.players[1] = c
+	match.decks[1] = m.Deck
+	match.match = om
+	match.ready = [2]bool{true, true}
+	s.matches[c] = match
+
+	go s.runMatchEvents(match)
+	return nil
+}
+
+func (s *Server) getMatch(c *Client) (*matchInfo, int, error) {
+	s.matchesLock.Lock()
+	defer s.matchesLock.Unlock()
+
+	match, ok := s.matches[c]
+	if !ok {
+		return nil, 0, errors.New("first create the match")
+	}
+	p, _ := match.player(c)
+	return match, p, nil
+}
+
+func (s *Server) runMatchEvents(match *matchInfo) {
+outer:
+	for e := range match.match.Events() {
+		if _, ok := e.(ocgcore.MatchEventGameEnd); ok {
+			match.lock.Lock()
+			match.duel = nil
+			match.waiting = -1
+			match.ready = [2]bool{}
+			match.lock.Unlock()
+		}
+
+		event := resultMatchEvent{Event: e}
+		switch e.(type) {
+		case ocgcore.MatchEventChooseFirst:
+			event.Type = "choose_first"
+		case ocgcore.MatchEventGameStart:
+			event.Type = "game_start"
+		case ocgcore.MatchEventGameEnd:
+			event.Type = "game_end"
+		case ocgcore.MatchEventEnd:
+			event.Type = "match_end"
+		}
+		for _, c := range match.clients() {
+			if err := s.sendClient(c, "match_event", event); err != nil {
+				s.kickClient(c, err)
+				break outer
+			}
+		}
+	}
+
+	if match.match.Result().Winner >= 0 {
+		_ = s.destroyMatch(match.players[0])
+	}
+}
+
+func (s *Server) chooseFirst(c *Client, m messageChooseFirst) error {
+	match, p, err := s.getMatch(c)
+	if err != nil {
+		return err
+	}
+	om := match.started()
+	if om == nil {
+		return errors.New("waiting for an opponent")
+	}
+	if err := om.ChooseFirst(p, m.First); err != nil {
+		return err
+	}
+
+	match.lock.Lock()
+	match.chosen = true
+	match.lock.Unlock()
+	return s.tryStartGame(match)
+}
+
+// sideDeck confirms the deck of a player for the next game. An empty payload
+// keeps the current deck.
+func (s *Server) sideDeck(c *Client, payload json.RawMessage) error {
+	match, p, err := s.getMatch(c)
+	if err != nil {
+		return err
+	}
+	om := match.started()
+	if om == nil {
+		return errors.New("waiting for an opponent")
+	}
+	if len(payload) > 0 && string(payload) != "null" {
+		var deck ocgcore.Deck
+		if err := json.Unmarshal(payload, &deck); err != nil {
+			return err
+		}
+		if err := om.SideDeck(p, deck); err != nil {
+			return err
+		}
+	}
+
+	match.lock.Lock()
+	match.ready[p] = true
+	match.lock.Unlock()
+	return s.tryStartGame(match)
+}
+
+func (s *Server) tryStartGame(match *matchInfo) error {
+	match.lock.Lock()
+	if !match.chosen || !match.ready[0] || !match.ready[1] {
+		match.lock.Unlock()
+		return nil
+	}
+	duel, messages, err := match.match.StartGame()
+	if err != nil {
+		match.lock.Unlock()
+		return err
+	}
+	match.duel = duel
+	match.chosen = false
+	match.lock.Unlock()
+
+	go func() {
+	outer:
+		for m1 := range messages {
+			if team, ok := ocgcore.ResponsePlayer(m1); ok {
+				match.lock.Lock()
+				match.waiting = match.match.Player(team)
+				match.responded = false
+				match.lock.Unlock()
+			} else if _, ok := m1.(ocgcore.MessageRetry); ok {
+				match.lock.Lock()
+				match.responded = false
+				match.lock.Unlock()
+			}
+
+			m2, _ := ocgcore.MessageToJSON(m1)
+			for _, c := range match.clients() {
+				err := s.sendClientRaw(c, "message", m2)
+				text := ocgcore.MessageText(m1, s.describer(c.Locale()))
+				if err == nil && text != "" {
+					err = s.sendClient(c, "message_text", resultMessageText{Text: text})
+				}
+				if err != nil {
+					s.kickClient(c, err)
+					break outer
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *Server) matchResponse(c *Client, payload json.RawMessage) error {
+	match, p, err := s.getMatch(c)
+	if err != nil {
+		return err
+	}
+
+	match.lock.Lock()
+	duel := match.duel
+	if duel == nil || match.waiting != p || match.responded {
+		match.lock.Unlock()
+		return errNotYourTurn
+	}
+	match.responded = true
+	match.lock.Unlock()
+
+	resp, err := ocgcore.JSONToResponse(payload)
+	if err != nil {
+		return err
+	}
+	duel.SendResponse(resp)
+	return nil
+}
+
+func (s *Server) destroyMatch(c *Client) error {
+	s.matchesLock.Lock()
+	match, ok := s.matches[c]
+	if !ok {
+		s.matchesLock.Unlock()
+		return errors.New("first create the match")
+	}
+	for _, player := range match.clients() {
+		delete(s.matches, player)
+	}
+	delete(s.matchRooms, match.id)
+	s.matchesLock.Unlock()
+
+	match.closeOnce.Do(func() {
+		if om := match.started(); om != nil {
+			om.Destroy()
+		}
+	})
+	return nil
+}
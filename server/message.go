@@ -1,9 +1,71 @@
 package server
 
+import "ocgcore"
+
 type resultDuelCreation struct {
-	Success bool `json:"success"`
+	Success     bool                    `json:"success"`
+	Duel        int                     `json:"duel"`
+	CoreVersion ocgcore.ProtocolVersion `json:"core_version"`
+}
+
+type resultError struct {
+	Error string `json:"error"`
+}
+
+type resultMessageText struct {
+	Text string `json:"text"`
 }
 
 type messageCard struct {
 	Card uint32 `json:"card"`
 }
+
+type messageReload struct {
+	Token string `json:"token"`
+}
+
+type messageSetLocale struct {
+	Locale string `json:"locale"`
+}
+
+type messageCreateDuel struct {
+	TeamSize [2]int `json:"team_size"`
+	Relay    bool   `json:"relay"`
+	Format   string `json:"format"`
+}
+
+type messageJoinDuel struct {
+	Duel int `json:"duel"`
+	Team int `json:"team"`
+	Slot int `json:"slot"`
+}
+
+type messageDeck struct {
+	Main  []uint32 `json:"main"`
+	Extra []uint32 `json:"extra"`
+}
+
+type resultMatchCreation struct {
+	Success bool `json:"success"`
+	Match   int  `json:"match"`
+}
+
+type resultMatchEvent struct {
+	Type  string             `json:"type"`
+	Event ocgcore.MatchEvent `json:"event"`
+}
+
+type messageCreateMatch struct {
+	BestOf int          `json:"best_of"`
+	Format string       `json:"format"`
+	Deck   ocgcore.Deck `json:"deck"`
+}
+
+type messageJoinMatch struct {
+	Match int          `json:"match"`
+	Deck  ocgcore.Deck `json:"deck"`
+}
+
+type messageChooseFirst struct {
+	First bool `json:"first"`
+}
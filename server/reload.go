@@ -0,0 +1,64 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log"
+	"ocgcore/database"
+)
+
+func (s *Server) cardDatabase() database.CardDatabase {
+	s.cardsLock.RLock()
+	defer s.cardsLock.RUnlock()
+	return s.cards
+}
+
+// Reload builds the card database again from the configured sources. Duels
+// that are running keep the cards they started with, since the database is
+// replaced and never modified. The script cache is purged with
+// Config.PurgeScripts, so new duels read the edited scripts.
+func (s *Server) Reload() (database.Diff, error) {
+	cards, err := s.loadCards()
+	if err != nil {
+		return database.Diff{}, err
+	}
+	return s.setCards(cards), nil
+}
+
+// loadCards builds a card database from the configured sources, without
+// touching the one in use.
+func (s *Server) loadCards() (database.CardDatabase, error) {
+	cards, err := database.Merge(s.config.Sources...)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.Strings != nil {
+		cards.SetArchetypes(s.config.Strings)
+	}
+	return cards, nil
+}
+
+// setCards replaces the card database and purges the script cache, it
+// returns what changed.
+func (s *Server) setCards(cards database.CardDatabase) database.Diff {
+	s.cardsLock.Lock()
+	old := s.cards
+	s.cards = cards
+	s.cardsLock.Unlock()
+
+	if s.config.PurgeScripts != nil {
+		s.config.PurgeScripts()
+	}
+
+	diff := cards.Compare(old)
+	if old != nil {
+		log.Printf("reloaded cards: %d added, %d removed, %d modified", len(diff.Added), len(diff.Removed), len(diff.Modified))
+	}
+	return diff
+}
+
+func (s *Server) isAdmin(token string) bool {
+	if s.config.AdminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) == 1
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"ocgcore/database"
+	"testing"
+)
+
+// blockingSource loads no cards, once per value sent on it.
+type blockingSource chan struct{}
+
+func (s blockingSource) LoadCards(db database.CardDatabase) error {
+	<-s
+	return nil
+}
+
+func TestReloadOffLoop(t *testing.T) {
+	source := make(blockingSource)
+	s := NewServer(Config{Sources: []database.CardSource{source}, AdminToken: "secret"})
+	go s.runServer()
+
+	c := &Client{server: s, send: make(chan []byte, 16)}
+	s.register <- c
+	send := func(m string) {
+		s.receive <- recvMessage{c: c, m: []byte(m)}
+	}
+
+	send(`{"action":"reload","payload":{"token":"secret"}}`)
+	// the loop keeps serving the clients while the sources are read
+	send(`{"action":"card","payload":{"card":1}}`)
+	expect(t, c, "card")
+	send(`{"action":"reload","payload":{"token":"secret"}}`)
+	expect(t, c, "error")
+
+	source <- struct{}{}
+	expect(t, c, "reload")
+	if s.cardDatabase() == nil {
+		t.Fatal("cards not replaced")
+	}
+}
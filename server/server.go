@@ -2,12 +2,12 @@ package server
 
 import (
 	"encoding/json"
-	"errors"
 	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
 	"ocgcore"
 	"ocgcore/database"
+	"sync"
 	"time"
 )
 
@@ -21,19 +21,56 @@ type unregisterErr struct {
 	e error
 }
 
+// reloadResult is a card database loaded for the reload action of a client.
+type reloadResult struct {
+	c     *Client
+	cards database.CardDatabase
+	err   error
+}
+
 type Server struct {
 	config     Config
 	unregister chan unregisterErr
 	register   chan *Client
 	receive    chan recvMessage
+	reloaded   chan reloadResult
 	clients    map[*Client]bool
-	duels      map[*Client]duelInfo
+	// reloading is set by runServer while a reload action loads the cards.
+	reloading bool
+
+	// coreVersion is set by Run, before serving the clients.
+	coreVersion ocgcore.ProtocolVersion
+
+	cardsLock sync.RWMutex
+	cards     database.CardDatabase
+
+	duelsLock  sync.Mutex
+	duels      map[*Client]*duelInfo
+	rooms      map[int]*duelInfo
+	lastDuelId int
+
+	matchesLock sync.Mutex
+	matches     map[*Client]*matchInfo
+	matchRooms  map[int]*matchInfo
+	lastMatchId int
 }
 
 type Config struct {
 	Address      string
 	ScriptReader ocgcore.ScriptReader
-	Database     database.CardDatabase
+	// PurgeScripts drops the scripts cached by ScriptReader, Reload calls
+	// it so that the next duels read the edited scripts.
+	PurgeScripts func()
+	// Sources are merged in order to build the card database.
+	Sources []database.CardSource
+	Strings *database.Strings
+	// AdminToken enables the reload action for the clients that send it.
+	AdminToken string
+	// Backend runs the duels, the core when nil.
+	Backend ocgcore.Backend
+	// LogHandler receives the core log of every duel. Script errors are
+	// logged anyway and kept in the duel diagnostics.
+	LogHandler ocgcore.LogHandler
 }
 
 func NewServer(c Config) *Server {
@@ -42,11 +79,46 @@ func NewServer(c Config) *Server {
 		unregister: make(chan unregisterErr),
 		register:   make(chan *Client),
 		receive:    make(chan recvMessage),
+		reloaded:   make(chan reloadResult),
 		clients:    map[*Client]bool{},
+		duels:      map[*Client]*duelInfo{},
+		rooms:      map[int]*duelInfo{},
+		matches:    map[*Client]*matchInfo{},
+		matchRooms: map[int]*matchInfo{},
+	}
+}
+
+func (s *Server) describer(locale string) database.Describer {
+	return database.Describer{Cards: s.cardDatabase(), Strings: s.config.Strings, Locale: locale}
+}
+
+// logHandler attributes the core log to a room, which is a duel or a match.
+func (s *Server) logHandler(room string, id int) ocgcore.LogHandler {
+	return func(entry ocgcore.LogEntry) {
+		if entry.Type == ocgcore.LogTypeError {
+			log.Printf("%s %d: script error: %s", room, id, entry.Message)
+		}
+		if s.config.LogHandler != nil {
+			s.config.LogHandler(entry)
+		}
 	}
 }
 
 func (s *Server) Run() error {
+	warnings, err := ocgcore.CheckCoreVersion(s.config.Backend)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		log.Printf("warning: %s", w)
+	}
+	if s.coreVersion, err = ocgcore.CoreVersion(s.config.Backend); err != nil {
+		return err
+	}
+	if _, err := s.Reload(); err != nil {
+		return err
+	}
+
 	go s.runServer()
 
 	mux := http.NewServeMux()
@@ -54,11 +126,6 @@ func (s *Server) Run() error {
 	return http.ListenAndServe(s.config.Address, mux)
 }
 
-type duelInfo struct {
-	duel *ocgcore.OcgDuel
-	done chan bool
-}
-
 type jsonMessage struct {
 	Action  string          `json:"action"`
 	Payload json.RawMessage `json:"payload"`
@@ -112,11 +179,27 @@ func (s *Server) runServer() {
 				log.Print("kicking client ", u.e)
 
 				_ = s.destroyDuel(u.c)
+				_ = s.destroyMatch(u.c)
 				_ = u.c.conn.Close()
 				delete(s.clients, u.c)
 				close(u.c.send)
 			}
 
+		case r := <-s.reloaded:
+			s.reloading = false
+			var diff database.Diff
+			if r.err == nil {
+				diff = s.setCards(r.cards)
+			}
+			if _, ok := s.clients[r.c]; !ok {
+				break
+			}
+			if r.err != nil {
+				_ = s.sendClient(r.c, "error", resultError{Error: r.err.Error()})
+				break
+			}
+			_ = s.sendClient(r.c, "reload", diff)
+
 		case msg := <-s.receive:
 			c := msg.c
 			var m jsonMessage
@@ -133,22 +216,83 @@ func (s *Server) runServer() {
 					s.kickClient(c, err)
 					break
 				}
-				_ = s.sendClient(c, "card", s.config.Database[msg.Card].Card)
+				card, _ := s.cardDatabase().Card(msg.Card, c.Locale())
+				_ = s.sendClient(c, "card", card)
+			case "reload":
+				var msg messageReload
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				if !s.isAdmin(msg.Token) {
+					_ = s.sendClient(c, "error", resultError{Error: "not allowed to reload"})
+					break
+				}
+				if s.reloading {
+					_ = s.sendClient(c, "error", resultError{Error: "reload already running"})
+					break
+				}
+				// the sources are read off the loop, the result comes back
+				// through s.reloaded
+				s.reloading = true
+				go func() {
+					cards, err := s.loadCards()
+					s.reloaded <- reloadResult{c: c, cards: cards, err: err}
+				}()
+			case "set_locale":
+				var msg messageSetLocale
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				c.setLocale(msg.Locale)
 
+			case "search_cards":
+				var msg database.SearchQuery
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				if msg.Locale == "" {
+					msg.Locale = c.Locale()
+				}
+				result, err := s.cardDatabase().Search(msg)
+				if err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+				_ = s.sendClient(c, "search_cards", result)
 			case "create_duel":
-				duel := ocgcore.CreateDuel(ocgcore.CreateDuelOptions{
-					Seed: 0,
-					Mode: ocgcore.DuelModeMR5,
-					CardReader: func(code uint32) (raw ocgcore.RawCardData) {
-						if card, ok := s.config.Database[code]; ok {
-							raw = card.Raw
-						}
-						return
-					},
-					ScriptReader: s.config.ScriptReader,
-				})
-				s.createDuel(c, duel)
-				_ = s.sendClient(c, "create_duel", resultDuelCreation{Success: true})
+				duel, err := s.createDuel(c, m.Payload)
+				if err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				_ = s.sendClient(c, "create_duel", resultDuelCreation{Success: true, Duel: duel.id, CoreVersion: s.coreVersion})
+			case "join_duel":
+				var msg messageJoinDuel
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				if err := s.joinDuel(c, msg); err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+			case "set_deck":
+				var msg messageDeck
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				duel, err := s.getDuel(c)
+				if err == nil {
+					err = duel.setDeck(c, &msg, s.cardDatabase().DeckCard)
+				}
+				if err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
 			case "start_duel":
 				err := s.startDuel(c)
 				if err != nil {
@@ -156,84 +300,86 @@ func (s *Server) runServer() {
 					break
 				}
 				// TODO: feedback
+			case "duel_diagnostics":
+				diagnostics, err := s.duelDiagnostics(c)
+				if err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+				_ = s.sendClient(c, "duel_diagnostics", diagnostics)
 			case "duel_response":
 				err := s.duelResponse(c, m.Payload)
+				if err == errNotYourTurn {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
 				if err != nil {
 					s.kickClient(c, err)
 					break
 				}
 				// TODO: feedback
-			case "quit_duel":
-				err := s.destroyDuel(c)
+			case "create_match":
+				var msg messageCreateMatch
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				match, err := s.createMatch(c, msg)
 				if err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+				_ = s.sendClient(c, "create_match", resultMatchCreation{Success: true, Match: match.id})
+			case "join_match":
+				var msg messageJoinMatch
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
 					s.kickClient(c, err)
 					break
 				}
-				// TODO: feedback
-			}
-		}
-	}
-}
-
-func (s *Server) createDuel(c *Client, duel *ocgcore.OcgDuel) {
-	s.duels[c] = duelInfo{duel: duel}
-}
-
-func (s *Server) startDuel(c *Client) error {
-	duel, ok := s.duels[c]
-	if !ok {
-		return errors.New("first create the duel")
-	}
-
-	duel.done = make(chan bool)
-	messages := duel.duel.Start()
-	go func() {
-	outer:
-		for {
-			select {
-			case m1, ok := <-messages:
-				if !ok {
-					break outer
+				if err := s.joinMatch(c, msg); err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
 				}
-
-				m2, _ := ocgcore.MessageToJSON(m1)
-				err := s.sendClientRaw(c, "message", m2)
-
+			case "choose_first":
+				var msg messageChooseFirst
+				if err := json.Unmarshal(m.Payload, &msg); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+				if err := s.chooseFirst(c, msg); err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+			case "side_deck":
+				if err := s.sideDeck(c, m.Payload); err != nil {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+			case "match_response":
+				err := s.matchResponse(c, m.Payload)
+				if err == errNotYourTurn {
+					_ = s.sendClient(c, "error", resultError{Error: err.Error()})
+					break
+				}
+				if err != nil {
+					s.kickClient(c, err)
+					break
+				}
+			case "quit_match":
+				if err := s.destroyMatch(c); err != nil {
+					s.kickClient(c, err)
+					break
+				}
+			case "quit_duel":
+				err := s.destroyDuel(c)
 				if err != nil {
 					s.kickClient(c, err)
-					break outer
+					break
 				}
-			case _, _ = <-duel.done:
-				break outer
+				// TODO: feedback
 			}
 		}
-		_ = s.destroyDuel(c)
-	}()
-	return nil
-}
-
-func (s *Server) destroyDuel(c *Client) error {
-	duel, ok := s.duels[c]
-	if !ok {
-		return errors.New("first create the duel")
-	}
-
-	close(duel.done)
-	duel.duel.Destroy()
-	return nil
-}
-
-func (s *Server) duelResponse(c *Client, payload json.RawMessage) error {
-	duel, ok := s.duels[c]
-	if !ok {
-		return errors.New("first create the duel")
 	}
-	resp, err := ocgcore.JSONToResponse(payload)
-	if err != nil {
-		return err
-	}
-	duel.duel.SendResponse(resp)
-	return nil
 }
 
 func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
@@ -262,5 +408,5 @@ const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	maxMessageSize = 4096
 )
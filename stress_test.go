@@ -0,0 +1,185 @@
+//go:build cgo
+// +build cgo
+
+package ocgcore_test
+
+import (
+	"fmt"
+	"math/rand"
+	"ocgcore"
+	"ocgcore/database"
+	"ocgcore/lib"
+	"ocgcore/script"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	stressMainDeckSize  = 40
+	stressExtraDeckSize = 15
+)
+
+type cardPool struct {
+	main  []uint32
+	extra []uint32
+}
+
+func newCardPool(db database.CardDatabase) cardPool {
+	var pool cardPool
+	for code, card := range db {
+		t := card.Raw.Type
+		switch {
+		case t&lib.CardTypeToken != 0 || card.Raw.Alias != 0:
+		case t&(lib.CardTypeFusion|lib.CardTypeSynchro|lib.CardTypeXyz|lib.CardTypeLink) != 0:
+			pool.extra = append(pool.extra, code)
+		default:
+			pool.main = append(pool.main, code)
+		}
+	}
+	return pool
+}
+
+func randomCards(r *rand.Rand, cards []uint32, n int) []uint32 {
+	deck := make([]uint32, 0, n)
+	for i := 0; i < n && len(cards) > 0; i++ {
+		deck = append(deck, cards[r.Intn(len(cards))])
+	}
+	return deck
+}
+
+// runDuel plays a duel until the first response is needed, which loads the
+// data and the scripts of every card in the decks.
+func runDuel(r *rand.Rand, pool cardPool, options ocgcore.CreateDuelOptions) (int, error) {
+	options.Seed = r.Uint32()
+	duel, err := ocgcore.CreateDuel(options)
+	if err != nil {
+		return 0, err
+	}
+	for player := 0; player < 2; player++ {
+		duel.SetupDeck(player, randomCards(r, pool.main, stressMainDeckSize), randomCards(r, pool.extra, stressExtraDeckSize), true)
+	}
+
+	messages := 0
+	for m := range duel.Start() {
+		messages++
+		if _, ok := m.(ocgcore.MessageWaitingResponse); ok {
+			break
+		}
+	}
+	duel.Destroy()
+	return messages, nil
+}
+
+// cgocheck2 reports whether the strictest cgo pointer checks are enabled.
+func cgocheck2() bool {
+	if strings.Contains(os.Getenv("GODEBUG"), "cgocheck=2") {
+		return true
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "GOEXPERIMENT" && strings.Contains(s.Value, "cgocheck2") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TestStress creates and destroys many duels with random decks, to catch
+// memory errors and leaked duels in the bindings. It needs the core, the card
+// databases and the scripts in the module root, and should be run with the
+// strictest cgo pointer checks. Before Go 1.21 it runs itself again with
+// GODEBUG=cgocheck=2 when they are not set; later versions enable them at
+// build time and skip the test otherwise:
+//
+//	GOEXPERIMENT=cgocheck2 go test -run Stress .
+func TestStress(t *testing.T) {
+	if err := lib.Load(""); err != nil {
+		t.Skipf("core unavailable: %v", err)
+	}
+	for _, name := range []string{"cards.cdb", "release.cdb", "script"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Skipf("%s unavailable: %v", name, err)
+		}
+	}
+
+	if !cgocheck2() {
+		var minor int
+		if _, err := fmt.Sscanf(runtime.Version(), "go1.%d", &minor); err != nil || minor >= 21 {
+			t.Skip("cgo pointer checks disabled, run with GOEXPERIMENT=cgocheck2")
+		}
+		args := []string{"-test.run=^TestStress$", "-test.v"}
+		if testing.Short() {
+			args = append(args, "-test.short")
+		}
+		cmd := exec.Command(os.Args[0], args...)
+		cmd.Env = append(os.Environ(), "GODEBUG="+strings.TrimPrefix(os.Getenv("GODEBUG")+",cgocheck=2", ","))
+		out, err := cmd.CombinedOutput()
+		t.Logf("%s", out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	duels, parallel := 1000, 4
+	if testing.Short() {
+		duels = 100
+	}
+
+	db, err := database.Merge(database.SQLite("cards.cdb"), database.SQLite("release.cdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := newCardPool(db)
+	options := ocgcore.CreateDuelOptions{
+		Mode:         ocgcore.DuelModeMR5,
+		CardReader:   db.Reader(),
+		ScriptReader: script.Cache(script.Dir("script"), 64<<20).Read,
+		LogHandler:   func(entry ocgcore.LogEntry) {},
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	messages := 0
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := range next {
+				n, err := runDuel(r, pool, options)
+				if err != nil {
+					t.Errorf("duel %d: %v", i, err)
+					continue
+				}
+				lock.Lock()
+				messages += n
+				lock.Unlock()
+			}
+		}(int64(w))
+	}
+	for i := 0; i < duels; i++ {
+		next <- i
+		if (i+1)%100 == 0 {
+			runtime.GC()
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	var mem runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&mem)
+	t.Logf("%d duels, %d messages, heap %d KiB", duels, messages, mem.HeapAlloc>>10)
+
+	if n := lib.ActiveDuels(); n != 0 {
+		t.Errorf("%d duels leaked", n)
+	}
+}
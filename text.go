@@ -0,0 +1,140 @@
+package ocgcore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resolver provides the names and strings needed to render messages as text.
+type Resolver interface {
+	CardName(code uint32) string
+	// Describe resolves a description, either a system string id or a card
+	// code multiplied by 16 plus the index of one of its strings.
+	Describe(desc uint64) string
+	VictoryName(reason int) string
+	CounterName(counter int) string
+}
+
+const (
+	hintEvent      = 1
+	hintMessage    = 2
+	hintSelectMsg  = 3
+	hintOpSelected = 4
+	hintCode       = 8
+	hintNumber     = 9
+	hintCard       = 10
+)
+
+func playerName(p int) string {
+	return fmt.Sprintf("Player %d", p+1)
+}
+
+func readable(s fmt.Stringer) string {
+	return strings.ReplaceAll(s.String(), "_", " ")
+}
+
+func cardNames(r Resolver, cards []CardInfo) string {
+	names := make([]string, len(cards))
+	for i, c := range cards {
+		names[i] = r.CardName(uint32(c.Code))
+	}
+	return strings.Join(names, ", ")
+}
+
+// MessageText renders a message as a line of text, for logs and chat. It
+// returns an empty string for messages that only matter to the client state.
+func MessageText(m Message, r Resolver) string {
+	switch m := m.(type) {
+	case MessageHint:
+		switch m.Hint {
+		case hintEvent, hintMessage, hintSelectMsg:
+			return r.Describe(m.Desc)
+		case hintOpSelected:
+			return fmt.Sprintf("%s selected %s", playerName(m.Player), r.Describe(m.Desc))
+		case hintCode, hintCard:
+			return fmt.Sprintf("%s declared %s", playerName(m.Player), r.CardName(uint32(m.Desc)))
+		case hintNumber:
+			return fmt.Sprintf("%s declared %d", playerName(m.Player), m.Desc)
+		}
+	case MessageWin:
+		reason := r.VictoryName(m.Reason)
+		if m.Player == 2 {
+			return "The duel ended in a draw"
+		}
+		if reason == "" {
+			return fmt.Sprintf("%s wins", playerName(m.Player))
+		}
+		return fmt.Sprintf("%s wins (%s)", playerName(m.Player), reason)
+	case MessageSelectBattleCMD:
+		return fmt.Sprintf("%s is choosing a battle action", playerName(m.Player))
+	case MessageSelectIdleCMD:
+		return fmt.Sprintf("%s is choosing an action", playerName(m.Player))
+	case MessageSelectEffectYN:
+		return fmt.Sprintf("%s: activate %s? (%s)", playerName(m.Player), r.CardName(m.Code), r.Describe(m.Description))
+	case MessageSelectYesNo:
+		return fmt.Sprintf("%s: %s?", playerName(m.Player), r.Describe(m.Description))
+	case MessageSelectOption:
+		options := make([]string, len(m.Options))
+		for i, o := range m.Options {
+			options[i] = r.Describe(o)
+		}
+		return fmt.Sprintf("%s chooses between: %s", playerName(m.Player), strings.Join(options, ", "))
+	case MessageSelectCard:
+		return fmt.Sprintf("%s selects %d to %d cards", playerName(m.Player), m.Min, m.Max)
+	case MessageSelectUnselectCard:
+		return fmt.Sprintf("%s selects %d to %d cards", playerName(m.Player), m.Min, m.Max)
+	case MessageSelectChain:
+		return fmt.Sprintf("%s can chain", playerName(m.Player))
+	case MessageSelectPlace:
+		return fmt.Sprintf("%s selects %d zones", playerName(m.Player), m.Count)
+	case MessageSelectDisfield:
+		return fmt.Sprintf("%s selects %d zones", playerName(m.Player), m.Count)
+	case MessageSelectPosition:
+		return fmt.Sprintf("%s chooses the position of %s", playerName(m.Player), r.CardName(m.Code))
+	case MessageSelectTribute:
+		return fmt.Sprintf("%s tributes %d to %d monsters", playerName(m.Player), m.Min, m.Max)
+	case MessageSelectCounter:
+		return fmt.Sprintf("%s removes %d %s", playerName(m.Player), m.Count, r.CounterName(m.CounterType))
+	case MessageSelectSum:
+		return fmt.Sprintf("%s selects cards for a total of %d", playerName(m.Player), m.Acc)
+	case MessageSortChain, MessageSortCard:
+		player, _ := ResponsePlayer(m)
+		return fmt.Sprintf("%s sorts cards", playerName(player))
+	case MessageConfirmDeckTop:
+		return fmt.Sprintf("%s reveals the top of their deck: %s", playerName(m.Player), cardNames(r, m.Cards))
+	case MessageConfirmCards:
+		return fmt.Sprintf("%s reveals %s", playerName(m.Player), cardNames(r, m.Cards))
+	case MessageShuffleDeck:
+		return fmt.Sprintf("%s shuffles their deck", playerName(m.Player))
+	case MessageNewTurn:
+		return fmt.Sprintf("Turn of %s", playerName(m.Player))
+	case MessageNewPhase:
+		return fmt.Sprintf("Entering the %s", readable(m.Phase))
+	case MessageMove:
+		if m.Card.Code == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s moves from %s to %s", r.CardName(uint32(m.Card.Code)), readable(m.Previous.Location), readable(m.Card.Location))
+	case MessageSummoning:
+		return fmt.Sprintf("%s normal summons %s", playerName(m.Card.Controller), r.CardName(uint32(m.Card.Code)))
+	case MessageSPSummoning:
+		return fmt.Sprintf("%s special summons %s", playerName(m.Card.Controller), r.CardName(uint32(m.Card.Code)))
+	case MessageChaining:
+		return fmt.Sprintf("%s activates %s (%s)", playerName(m.Card.Controller), r.CardName(uint32(m.Card.Code)), r.Describe(m.Description))
+	case MessageChainSolving:
+		return fmt.Sprintf("Chain link %d resolves", m.Count)
+	case MessageChainEnd:
+		return "The chain ends"
+	case MessageBecomeTarget:
+		return fmt.Sprintf("%d cards targeted", len(m.Targets))
+	case MessageDraw:
+		return fmt.Sprintf("%s draws %d cards", playerName(m.Player), len(m.Cards))
+	case MessageTagSwap:
+		return fmt.Sprintf("%s swaps duelist", playerName(m.Player))
+	case MessageAIName:
+		return m.Name
+	case MessageShowHint:
+		return m.Hint
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package tournament
+
+import "errors"
+
+// StartTopCut ends the Swiss rounds and pairs the best size players in a
+// single elimination bracket, the first seed against the last one and so on.
+func (t *Tournament) StartTopCut(size int) (*Round, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if size < 2 || size&(size-1) != 0 {
+		return nil, errors.New("top cut size must be a power of two")
+	}
+	r := t.currentRound()
+	if r == nil {
+		return nil, errors.New("no swiss round played")
+	}
+	if r.Elimination {
+		return nil, errors.New("top cut already started")
+	}
+	if !r.Done() {
+		return nil, errors.New("current round not done")
+	}
+
+	var seeds []int
+	for _, s := range t.standings() {
+		if !t.Players[s.Player].Dropped {
+			seeds = append(seeds, s.Player)
+		}
+	}
+	if len(seeds) < size {
+		return nil, errors.New("not enough players for the top cut")
+	}
+	seeds = seeds[:size]
+	t.TopCut = size
+
+	round := &Round{Number: len(t.Rounds) + 1, Elimination: true}
+	for _, seed := range bracketOrder(size) {
+		round.Pairings = append(round.Pairings, &Pairing{
+			Table:   len(round.Pairings) + 1,
+			Players: [2]int{seeds[seed[0]], seeds[seed[1]]},
+		})
+	}
+	t.Rounds = append(t.Rounds, round)
+	return round, nil
+}
+
+// bracketOrder returns the seed pairs of the first elimination round, ordered
+// so that the top seeds can only meet in the last rounds.
+func bracketOrder(size int) [][2]int {
+	order := []int{0}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n-1-s)
+		}
+		order = next
+	}
+
+	pairs := make([][2]int, 0, size/2)
+	for i := 0; i < len(order); i += 2 {
+		pairs = append(pairs, [2]int{order[i], order[i+1]})
+	}
+	return pairs
+}
+
+// NextElimination pairs the winners of the current elimination round. It
+// returns nil once the final has been played.
+func (t *Tournament) NextElimination() (*Round, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	r := t.currentRound()
+	if r == nil || !r.Elimination {
+		return nil, errors.New("top cut not started")
+	}
+	if !r.Done() {
+		return nil, errors.New("current round not done")
+	}
+	if len(r.Pairings) == 1 {
+		return nil, nil
+	}
+
+	round := &Round{Number: len(t.Rounds) + 1, Elimination: true}
+	for i := 0; i < len(r.Pairings); i += 2 {
+		a, b := r.Pairings[i], r.Pairings[i+1]
+		round.Pairings = append(round.Pairings, &Pairing{
+			Table:   len(round.Pairings) + 1,
+			Players: [2]int{a.Players[a.Winner], b.Players[b.Winner]},
+		})
+	}
+	t.Rounds = append(t.Rounds, round)
+	return round, nil
+}
+
+// Winner returns the winner of the top cut final.
+func (t *Tournament) Winner() (*Player, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	r := t.currentRound()
+	if r == nil || !r.Elimination || len(r.Pairings) != 1 || !r.Pairings[0].Reported {
+		return nil, false
+	}
+	p := r.Pairings[0]
+	return t.Players[p.Players[p.Winner]], true
+}
@@ -0,0 +1,211 @@
+package tournament
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"ocgcore"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS tournaments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	format TEXT NOT NULL,
+	top_cut INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS players (
+	tournament INTEGER NOT NULL,
+	id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	deck TEXT NOT NULL,
+	dropped INTEGER NOT NULL,
+	PRIMARY KEY (tournament, id)
+);
+CREATE TABLE IF NOT EXISTS pairings (
+	tournament INTEGER NOT NULL,
+	round INTEGER NOT NULL,
+	elimination INTEGER NOT NULL,
+	table_number INTEGER NOT NULL,
+	player1 INTEGER NOT NULL,
+	player2 INTEGER NOT NULL,
+	reported INTEGER NOT NULL,
+	wins1 INTEGER NOT NULL,
+	wins2 INTEGER NOT NULL,
+	draws INTEGER NOT NULL,
+	winner INTEGER NOT NULL,
+	PRIMARY KEY (tournament, round, table_number)
+);
+CREATE TABLE IF NOT EXISTS standings (
+	tournament INTEGER NOT NULL,
+	rank INTEGER NOT NULL,
+	player INTEGER NOT NULL,
+	points INTEGER NOT NULL,
+	matches INTEGER NOT NULL,
+	wins INTEGER NOT NULL,
+	losses INTEGER NOT NULL,
+	draws INTEGER NOT NULL,
+	omw REAL NOT NULL,
+	gw REAL NOT NULL,
+	ogw REAL NOT NULL,
+	PRIMARY KEY (tournament, rank)
+);`
+
+// Store persists tournaments and their standings to a SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+func OpenStore(fileName string) (*Store, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", fileName))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save writes the whole state of a tournament, assigning its ID on the first
+// save.
+func (s *Store) Save(t *Tournament) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := s.save(tx, t); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) save(tx *sql.Tx, t *Tournament) error {
+	if t.ID == 0 {
+		res, err := tx.Exec(`INSERT INTO tournaments (name, format, top_cut) VALUES (?, ?, ?)`, t.Name, t.Format.Name, t.TopCut)
+		if err != nil {
+			return err
+		}
+		if t.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+	} else {
+		_, err := tx.Exec(`UPDATE tournaments SET name = ?, format = ?, top_cut = ? WHERE id = ?`, t.Name, t.Format.Name, t.TopCut, t.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"players", "pairings", "standings"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE tournament = ?`, t.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range t.Players {
+		deck, err := json.Marshal(p.Deck)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO players (tournament, id, name, deck, dropped) VALUES (?, ?, ?, ?, ?)`,
+			t.ID, p.ID, p.Name, string(deck), p.Dropped)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range t.Rounds {
+		for _, p := range r.Pairings {
+			_, err := tx.Exec(`
+INSERT INTO pairings (tournament, round, elimination, table_number, player1, player2, reported, wins1, wins2, draws, winner)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				t.ID, r.Number, r.Elimination, p.Table, p.Players[0], p.Players[1], p.Reported, p.Wins[0], p.Wins[1], p.Draws, p.Winner)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, st := range t.standings() {
+		_, err := tx.Exec(`
+INSERT INTO standings (tournament, rank, player, points, matches, wins, losses, draws, omw, gw, ogw)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.ID, i+1, st.Player, st.Points, st.Matches, st.Wins, st.Losses, st.Draws,
+			st.OpponentsMatchWinPercentage, st.GameWinPercentage, st.OpponentsGameWinPercentage)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads a tournament saved with Save. Its format must be one of the
+// preset formats.
+func (s *Store) Load(id int64) (*Tournament, error) {
+	t := &Tournament{ID: id}
+
+	var formatName string
+	err := s.db.QueryRow(`SELECT name, format, top_cut FROM tournaments WHERE id = ?`, id).Scan(&t.Name, &formatName, &t.TopCut)
+	if err != nil {
+		return nil, err
+	}
+	format, ok := ocgcore.FormatByName(formatName)
+	if !ok {
+		return nil, fmt.Errorf("tournament %d: unknown format %s", id, formatName)
+	}
+	t.Format = format
+
+	players, err := s.db.Query(`SELECT id, name, deck, dropped FROM players WHERE tournament = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer players.Close()
+	for players.Next() {
+		p := &Player{}
+		var deck string
+		if err := players.Scan(&p.ID, &p.Name, &deck, &p.Dropped); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(deck), &p.Deck); err != nil {
+			return nil, fmt.Errorf("tournament %d: player %d: %w", id, p.ID, err)
+		}
+		t.Players = append(t.Players, p)
+	}
+	if err := players.Err(); err != nil {
+		return nil, err
+	}
+
+	pairings, err := s.db.Query(`
+SELECT round, elimination, table_number, player1, player2, reported, wins1, wins2, draws, winner
+FROM pairings WHERE tournament = ? ORDER BY round, table_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer pairings.Close()
+	for pairings.Next() {
+		p := &Pairing{}
+		var round int
+		var elimination bool
+		err := pairings.Scan(&round, &elimination, &p.Table, &p.Players[0], &p.Players[1], &p.Reported, &p.Wins[0], &p.Wins[1], &p.Draws, &p.Winner)
+		if err != nil {
+			return nil, err
+		}
+		if len(t.Rounds) < round {
+			t.Rounds = append(t.Rounds, &Round{Number: round, Elimination: elimination})
+		}
+		r := t.Rounds[len(t.Rounds)-1]
+		r.Pairings = append(r.Pairings, p)
+	}
+	return t, pairings.Err()
+}
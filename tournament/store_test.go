@@ -0,0 +1,78 @@
+//go:build cgo
+// +build cgo
+
+package tournament
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "tournaments.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	tr := newTestTournament(9)
+	for round := 0; round < 3; round++ {
+		if _, err := tr.PairSwiss(); err != nil {
+			t.Fatal(err)
+		}
+		reportRound(t, tr)
+	}
+	if err := tr.Drop(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.StartTopCut(4); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() {
+		t.Helper()
+		loaded, err := store.Load(tr.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.ID != tr.ID || loaded.Name != tr.Name || loaded.Format.Name != tr.Format.Name || loaded.TopCut != tr.TopCut {
+			t.Errorf("loaded %d %q %q %d, want %d %q %q %d", loaded.ID, loaded.Name, loaded.Format.Name, loaded.TopCut, tr.ID, tr.Name, tr.Format.Name, tr.TopCut)
+		}
+		if !reflect.DeepEqual(loaded.Players, tr.Players) {
+			t.Errorf("loaded players %+v, want %+v", loaded.Players, tr.Players)
+		}
+		if !reflect.DeepEqual(loaded.Rounds, tr.Rounds) {
+			t.Errorf("loaded rounds differ")
+		}
+		if !reflect.DeepEqual(loaded.Standings(), tr.Standings()) {
+			t.Errorf("loaded standings differ")
+		}
+	}
+
+	if err := store.Save(tr); err != nil {
+		t.Fatal(err)
+	}
+	if tr.ID == 0 {
+		t.Fatal("no ID assigned")
+	}
+	check()
+
+	// saving again replaces the state
+	id := tr.ID
+	reportRound(t, tr)
+	if _, err := tr.NextElimination(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(tr); err != nil {
+		t.Fatal(err)
+	}
+	if tr.ID != id {
+		t.Errorf("ID changed from %d to %d", id, tr.ID)
+	}
+	check()
+
+	if _, err := store.Load(id + 1); err == nil {
+		t.Error("loaded an unknown tournament")
+	}
+}
@@ -0,0 +1,248 @@
+package tournament
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+type Standing struct {
+	Player  int `json:"player"`
+	Points  int `json:"points"`
+	Matches int `json:"matches"`
+	Wins    int `json:"wins"`
+	Losses  int `json:"losses"`
+	Draws   int `json:"draws"`
+
+	MatchWinPercentage          float64 `json:"match_win_percentage"`
+	GameWinPercentage           float64 `json:"game_win_percentage"`
+	OpponentsMatchWinPercentage float64 `json:"opponents_match_win_percentage"`
+	OpponentsGameWinPercentage  float64 `json:"opponents_game_win_percentage"`
+
+	opponents  []int
+	hadBye     bool
+	games      int
+	gamePoints int
+}
+
+// Standings returns the Swiss standings, sorted by points, opponents' match
+// win percentage, game win percentage and opponents' game win percentage.
+func (t *Tournament) Standings() []Standing {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.standings()
+}
+
+func (t *Tournament) standings() []Standing {
+	standings := make([]Standing, len(t.Players))
+	for i := range standings {
+		standings[i].Player = i
+	}
+
+	for _, r := range t.Rounds {
+		if r.Elimination {
+			continue
+		}
+		for _, p := range r.Pairings {
+			if !p.Reported {
+				continue
+			}
+			if p.Bye() {
+				s := &standings[p.Players[0]]
+				s.hadBye = true
+				s.Matches++
+				s.Wins++
+				s.Points += pointsWin
+				s.games += 2
+				s.gamePoints += 2 * pointsWin
+				continue
+			}
+			for i, player := range p.Players {
+				s := &standings[player]
+				s.opponents = append(s.opponents, p.Players[1-i])
+				s.Matches++
+				switch p.Winner {
+				case i:
+					s.Wins++
+					s.Points += pointsWin
+				case 2:
+					s.Draws++
+					s.Points += pointsDraw
+				default:
+					s.Losses++
+				}
+				s.games += p.Wins[0] + p.Wins[1] + p.Draws
+				s.gamePoints += p.Wins[i]*pointsWin + p.Draws*pointsDraw
+			}
+		}
+	}
+
+	for i := range standings {
+		s := &standings[i]
+		s.MatchWinPercentage = winPercentage(s.Points, s.Matches)
+		s.GameWinPercentage = winPercentage(s.gamePoints, s.games)
+	}
+	for i := range standings {
+		s := &standings[i]
+		if len(s.opponents) == 0 {
+			continue
+		}
+		for _, o := range s.opponents {
+			s.OpponentsMatchWinPercentage += standings[o].MatchWinPercentage
+			s.OpponentsGameWinPercentage += standings[o].GameWinPercentage
+		}
+		s.OpponentsMatchWinPercentage /= float64(len(s.opponents))
+		s.OpponentsGameWinPercentage /= float64(len(s.opponents))
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.OpponentsMatchWinPercentage != b.OpponentsMatchWinPercentage {
+			return a.OpponentsMatchWinPercentage > b.OpponentsMatchWinPercentage
+		}
+		if a.GameWinPercentage != b.GameWinPercentage {
+			return a.GameWinPercentage > b.GameWinPercentage
+		}
+		return a.OpponentsGameWinPercentage > b.OpponentsGameWinPercentage
+	})
+	return standings
+}
+
+func winPercentage(points int, matches int) float64 {
+	if matches == 0 {
+		return 0
+	}
+	p := float64(points) / float64(matches*pointsWin)
+	if p < minWinPercentage {
+		return minWinPercentage
+	}
+	return p
+}
+
+// PairSwiss starts a new Swiss round. Players are paired by standing,
+// avoiding rematches when possible. With an odd number of players, the
+// lowest ranked player that didn't have a bye yet gets one.
+func (t *Tournament) PairSwiss() (*Round, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if r := t.currentRound(); r != nil {
+		if r.Elimination {
+			return nil, errors.New("swiss rounds are over")
+		}
+		if !r.Done() {
+			return nil, errors.New("current round not done")
+		}
+	}
+
+	standings := t.standings()
+	if len(t.Rounds) == 0 {
+		rand.Shuffle(len(standings), func(i, j int) {
+			standings[i], standings[j] = standings[j], standings[i]
+		})
+	}
+
+	var active []*Standing
+	for i := range standings {
+		if !t.Players[standings[i].Player].Dropped {
+			active = append(active, &standings[i])
+		}
+	}
+	if len(active) < 2 {
+		return nil, errors.New("not enough players")
+	}
+
+	round := &Round{Number: len(t.Rounds) + 1}
+
+	var bye *Standing
+	if len(active)%2 == 1 {
+		bye = active[len(active)-1]
+		for i := len(active) - 1; i >= 0; i-- {
+			if !active[i].hadBye {
+				bye = active[i]
+				break
+			}
+		}
+		for i, s := range active {
+			if s == bye {
+				active = append(active[:i], active[i+1:]...)
+				break
+			}
+		}
+	}
+
+	played := map[[2]int]bool{}
+	for _, s := range standings {
+		for _, o := range s.opponents {
+			played[[2]int{s.Player, o}] = true
+		}
+	}
+
+	players := make([]int, len(active))
+	for i, s := range active {
+		players[i] = s.Player
+	}
+	steps := maxPairingSteps
+	pairs, ok := pairPlayers(players, played, &steps)
+	if !ok {
+		// everyone already played each other, pair by standing
+		pairs = nil
+		for i := 0; i < len(players); i += 2 {
+			pairs = append(pairs, [2]int{players[i], players[i+1]})
+		}
+	}
+
+	for _, pair := range pairs {
+		round.Pairings = append(round.Pairings, &Pairing{
+			Table:   len(round.Pairings) + 1,
+			Players: pair,
+		})
+	}
+	if bye != nil {
+		round.Pairings = append(round.Pairings, &Pairing{
+			Table:    len(round.Pairings) + 1,
+			Players:  [2]int{bye.Player, -1},
+			Reported: true,
+			Wins:     [2]int{2, 0},
+			Winner:   0,
+		})
+	}
+
+	t.Rounds = append(t.Rounds, round)
+	return round, nil
+}
+
+// maxPairingSteps bounds the pairing search, late rounds of small events can
+// have no pairing without rematches and the search is exponential.
+const maxPairingSteps = 100000
+
+// pairPlayers pairs each player with the highest ranked opponent they didn't
+// play yet, backtracking when the remaining players can't be paired.
+func pairPlayers(players []int, played map[[2]int]bool, steps *int) ([][2]int, bool) {
+	if len(players) == 0 {
+		return nil, true
+	}
+	if *steps <= 0 {
+		return nil, false
+	}
+	*steps--
+
+	first := players[0]
+	for i := 1; i < len(players); i++ {
+		opponent := players[i]
+		if played[[2]int{first, opponent}] {
+			continue
+		}
+
+		rest := make([]int, 0, len(players)-2)
+		rest = append(rest, players[1:i]...)
+		rest = append(rest, players[i+1:]...)
+		if pairs, ok := pairPlayers(rest, played, steps); ok {
+			return append([][2]int{{first, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
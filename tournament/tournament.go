@@ -0,0 +1,189 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"ocgcore"
+	"sync"
+)
+
+const (
+	pointsWin  = 3
+	pointsDraw = 1
+
+	// minimum match and game win percentage, so that losing opponents don't
+	// weigh too much on the tiebreakers.
+	minWinPercentage = 1.0 / 3
+)
+
+type Player struct {
+	ID      int          `json:"id"`
+	Name    string       `json:"name"`
+	Deck    ocgcore.Deck `json:"deck"`
+	Dropped bool         `json:"dropped"`
+}
+
+// Pairing is a match between two players. Players[1] is -1 for a bye, which
+// counts as a 2-0 win.
+type Pairing struct {
+	Table    int    `json:"table"`
+	Players  [2]int `json:"players"`
+	Reported bool   `json:"reported"`
+	Wins     [2]int `json:"wins"`
+	Draws    int    `json:"draws"`
+	// Winner is the index in Players of the winner, 2 for a draw.
+	Winner int `json:"winner"`
+}
+
+func (p *Pairing) Bye() bool {
+	return p.Players[1] < 0
+}
+
+type Round struct {
+	Number      int        `json:"number"`
+	Elimination bool       `json:"elimination"`
+	Pairings    []*Pairing `json:"pairings"`
+}
+
+func (r *Round) Done() bool {
+	for _, p := range r.Pairings {
+		if !p.Reported {
+			return false
+		}
+	}
+	return true
+}
+
+// Tournament runs Swiss rounds followed by an optional single elimination
+// top cut.
+type Tournament struct {
+	ID     int64          `json:"id"`
+	Name   string         `json:"name"`
+	Format ocgcore.Format `json:"format"`
+	TopCut int            `json:"top_cut"`
+
+	Players []*Player `json:"players"`
+	Rounds  []*Round  `json:"rounds"`
+
+	lock sync.Mutex
+}
+
+func New(name string, format ocgcore.Format) *Tournament {
+	return &Tournament{
+		Name:   name,
+		Format: format,
+	}
+}
+
+// AddPlayer registers a player, cards is used to check the deck against the
+// tournament format.
+func (t *Tournament) AddPlayer(name string, deck ocgcore.Deck, cards func(code uint32) (ocgcore.DeckCard, bool)) (*Player, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.Rounds) > 0 {
+		return nil, errors.New("tournament already started")
+	}
+	if err := t.Format.ValidateDeck(deck.Main, deck.Extra, cards); err != nil {
+		return nil, fmt.Errorf("player %s: %w", name, err)
+	}
+	p := &Player{
+		ID:   len(t.Players),
+		Name: name,
+		Deck: deck,
+	}
+	t.Players = append(t.Players, p)
+	return p, nil
+}
+
+// Drop removes a player from the next Swiss rounds.
+func (t *Tournament) Drop(player int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if player < 0 || player >= len(t.Players) {
+		return errors.New("player not found")
+	}
+	t.Players[player].Dropped = true
+	return nil
+}
+
+func (t *Tournament) currentRound() *Round {
+	if len(t.Rounds) == 0 {
+		return nil
+	}
+	return t.Rounds[len(t.Rounds)-1]
+}
+
+func (t *Tournament) pairing(round int, table int) (*Pairing, error) {
+	if round < 1 || round > len(t.Rounds) {
+		return nil, errors.New("round not found")
+	}
+	r := t.Rounds[round-1]
+	if table < 1 || table > len(r.Pairings) {
+		return nil, errors.New("table not found")
+	}
+	return r.Pairings[table-1], nil
+}
+
+// NewMatch creates the best-of-three match of a pairing, with the decks of
+// the paired players. The returned match players follow the pairing order.
+func (t *Tournament) NewMatch(round int, table int, duel ocgcore.CreateDuelOptions) (*ocgcore.Match, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	p, err := t.pairing(round, table)
+	if err != nil {
+		return nil, err
+	}
+	if p.Bye() {
+		return nil, errors.New("byes are not played")
+	}
+	if p.Reported {
+		return nil, errors.New("match already reported")
+	}
+
+	format := t.Format
+	duel.Format = &format
+	return ocgcore.NewMatch(ocgcore.MatchOptions{
+		Duel:   duel,
+		BestOf: 3,
+		Decks: [2]ocgcore.Deck{
+			t.Players[p.Players[0]].Deck,
+			t.Players[p.Players[1]].Deck,
+		},
+		Chooser: rand.Intn(2),
+	})
+}
+
+// ReportMatch records the result of a match created by NewMatch.
+func (t *Tournament) ReportMatch(round int, table int, result ocgcore.MatchResult) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	p, err := t.pairing(round, table)
+	if err != nil {
+		return err
+	}
+	if p.Bye() {
+		return errors.New("byes are not played")
+	}
+	if result.Winner < 0 {
+		return errors.New("match not ended")
+	}
+	if t.Rounds[round-1].Elimination && result.Winner == 2 {
+		return errors.New("elimination matches need a winner")
+	}
+
+	p.Wins = result.Wins
+	p.Draws = 0
+	for _, g := range result.Games {
+		if g.Winner == 2 {
+			p.Draws++
+		}
+	}
+	p.Winner = result.Winner
+	p.Reported = true
+	return nil
+}
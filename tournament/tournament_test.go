@@ -0,0 +1,348 @@
+package tournament
+
+import (
+	"fmt"
+	"ocgcore"
+	"reflect"
+	"testing"
+)
+
+func newTestTournament(players int) *Tournament {
+	t := New("test", ocgcore.FormatMasterRule)
+	for i := 0; i < players; i++ {
+		t.Players = append(t.Players, &Player{
+			ID:   i,
+			Name: fmt.Sprintf("player %d", i),
+			Deck: ocgcore.Deck{Main: []uint32{uint32(i + 1)}, Extra: []uint32{}, Side: []uint32{}},
+		})
+	}
+	return t
+}
+
+// reportRound reports every match of the current round as a 2-1 win of the
+// first player of the pairing.
+func reportRound(t *testing.T, tr *Tournament) {
+	t.Helper()
+	r := tr.currentRound()
+	for _, p := range r.Pairings {
+		if p.Bye() {
+			continue
+		}
+		err := tr.ReportMatch(r.Number, p.Table, ocgcore.MatchResult{Wins: [2]int{2, 1}, Winner: 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkRound checks that every active player is paired once and that dropped
+// players are not paired, and returns the bye pairing if any.
+func checkRound(t *testing.T, tr *Tournament, r *Round) *Pairing {
+	t.Helper()
+	var bye *Pairing
+	seen := map[int]bool{}
+	for i, p := range r.Pairings {
+		if p.Table != i+1 {
+			t.Errorf("pairing %d at table %d", i, p.Table)
+		}
+		for _, player := range p.Players {
+			if player < 0 {
+				continue
+			}
+			if seen[player] {
+				t.Errorf("round %d: player %d paired twice", r.Number, player)
+			}
+			if tr.Players[player].Dropped {
+				t.Errorf("round %d: dropped player %d paired", r.Number, player)
+			}
+			seen[player] = true
+		}
+		if p.Bye() {
+			if bye != nil {
+				t.Errorf("round %d: two byes", r.Number)
+			}
+			bye = p
+		}
+	}
+	for _, p := range tr.Players {
+		if !p.Dropped && !seen[p.ID] {
+			t.Errorf("round %d: player %d not paired", r.Number, p.ID)
+		}
+	}
+	return bye
+}
+
+func TestPairPlayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []int
+		played  [][2]int
+		pairs   [][2]int
+		ok      bool
+	}{
+		{"no rematch", []int{0, 1, 2, 3}, nil, [][2]int{{0, 1}, {2, 3}}, true},
+		{"first rematch", []int{0, 1, 2, 3}, [][2]int{{0, 1}}, [][2]int{{0, 2}, {1, 3}}, true},
+		{"backtrack", []int{0, 1, 2, 3}, [][2]int{{2, 3}}, [][2]int{{0, 2}, {1, 3}}, true},
+		{"backtrack twice", []int{0, 1, 2, 3}, [][2]int{{2, 3}, {0, 2}}, [][2]int{{0, 3}, {1, 2}}, true},
+		{"standing order", []int{3, 0, 2, 1}, nil, [][2]int{{3, 0}, {2, 1}}, true},
+		{"all rematches", []int{0, 1, 2, 3}, [][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}, nil, false},
+	}
+	for _, test := range tests {
+		played := map[[2]int]bool{}
+		for _, p := range test.played {
+			played[p] = true
+			played[[2]int{p[1], p[0]}] = true
+		}
+		steps := maxPairingSteps
+		pairs, ok := pairPlayers(test.players, played, &steps)
+		if ok != test.ok || !reflect.DeepEqual(pairs, test.pairs) {
+			t.Errorf("%s: got %v, %v, want %v, %v", test.name, pairs, ok, test.pairs, test.ok)
+		}
+	}
+}
+
+func TestPairPlayersStepCap(t *testing.T) {
+	// two groups of odd size where every player already met the other group:
+	// no pairing exists, and proving it means trying every pairing of the
+	// first group
+	var players []int
+	played := map[[2]int]bool{}
+	for i := 0; i < 30; i++ {
+		players = append(players, i)
+		for j := 0; j < 30; j++ {
+			if (i < 15) != (j < 15) {
+				played[[2]int{i, j}] = true
+			}
+		}
+	}
+
+	steps := maxPairingSteps
+	if pairs, ok := pairPlayers(players, played, &steps); ok {
+		t.Fatalf("paired %v", pairs)
+	}
+	if steps > 0 {
+		t.Errorf("search ended with %d steps left, want the cap reached", steps)
+	}
+
+	steps = 0
+	if _, ok := pairPlayers([]int{0, 1}, map[[2]int]bool{}, &steps); ok {
+		t.Error("paired without steps left")
+	}
+}
+
+func TestPairSwissBye(t *testing.T) {
+	tr := newTestTournament(5)
+	byes := map[int]bool{}
+	for round := 1; round <= 5; round++ {
+		want := -1
+		if round > 1 {
+			standings := tr.standings()
+			for i := len(standings) - 1; i >= 0; i-- {
+				if !standings[i].hadBye {
+					want = standings[i].Player
+					break
+				}
+			}
+		}
+
+		r, err := tr.PairSwiss()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bye := checkRound(t, tr, r)
+		if bye == nil {
+			t.Fatalf("round %d: no bye with 5 players", round)
+		}
+		if bye.Table != len(r.Pairings) || !bye.Reported || bye.Wins != [2]int{2, 0} || bye.Winner != 0 {
+			t.Errorf("round %d: bye %+v", round, bye)
+		}
+		if want >= 0 && bye.Players[0] != want {
+			t.Errorf("round %d: bye to %d, want the lowest ranked player without a bye %d", round, bye.Players[0], want)
+		}
+		if byes[bye.Players[0]] {
+			t.Errorf("round %d: second bye to %d", round, bye.Players[0])
+		}
+		byes[bye.Players[0]] = true
+		reportRound(t, tr)
+	}
+
+	// everyone had a bye, it goes to the last player
+	standings := tr.standings()
+	r, err := tr.PairSwiss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bye := checkRound(t, tr, r); bye == nil || bye.Players[0] != standings[len(standings)-1].Player {
+		t.Errorf("bye %+v, want the last player %d", bye, standings[len(standings)-1].Player)
+	}
+}
+
+func TestPairSwissDrop(t *testing.T) {
+	tr := newTestTournament(6)
+	if _, err := tr.PairSwiss(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.PairSwiss(); err == nil {
+		t.Error("paired a round before the previous one is done")
+	}
+	reportRound(t, tr)
+
+	for _, drops := range [][]int{{1, 4}, {0}, {2, 3}} {
+		for _, player := range drops {
+			if err := tr.Drop(player); err != nil {
+				t.Fatal(err)
+			}
+		}
+		active := 0
+		for _, p := range tr.Players {
+			if !p.Dropped {
+				active++
+			}
+		}
+
+		r, err := tr.PairSwiss()
+		if active < 2 {
+			if err == nil {
+				t.Errorf("paired %d players", active)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bye := checkRound(t, tr, r); (bye != nil) != (active%2 == 1) {
+			t.Errorf("%d players, bye %+v", active, bye)
+		}
+		reportRound(t, tr)
+	}
+	if err := tr.Drop(6); err == nil {
+		t.Error("dropped an unknown player")
+	}
+}
+
+func TestPairSwissRematches(t *testing.T) {
+	tr := newTestTournament(4)
+	played := map[[2]int]bool{}
+	for round := 1; round <= 3; round++ {
+		r, err := tr.PairSwiss()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRound(t, tr, r)
+		for _, p := range r.Pairings {
+			if played[p.Players] {
+				t.Errorf("round %d: rematch %v", round, p.Players)
+			}
+			played[p.Players] = true
+			played[[2]int{p.Players[1], p.Players[0]}] = true
+		}
+		reportRound(t, tr)
+	}
+
+	// every remaining pair is a rematch, players are paired by standing
+	standings := tr.standings()
+	r, err := tr.PairSwiss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRound(t, tr, r)
+	want := [][2]int{
+		{standings[0].Player, standings[1].Player},
+		{standings[2].Player, standings[3].Player},
+	}
+	for i, p := range r.Pairings {
+		if p.Players != want[i] {
+			t.Errorf("table %d: %v, want %v", p.Table, p.Players, want[i])
+		}
+	}
+}
+
+func TestBracketOrder(t *testing.T) {
+	tests := map[int][][2]int{
+		2: {{0, 1}},
+		4: {{0, 3}, {1, 2}},
+		8: {{0, 7}, {3, 4}, {1, 6}, {2, 5}},
+	}
+	for size, want := range tests {
+		if got := bracketOrder(size); !reflect.DeepEqual(got, want) {
+			t.Errorf("bracketOrder(%d) = %v, want %v", size, got, want)
+		}
+	}
+}
+
+func TestTopCut(t *testing.T) {
+	tr := newTestTournament(11)
+	if _, err := tr.StartTopCut(8); err == nil {
+		t.Error("top cut started before the swiss rounds")
+	}
+	for round := 0; round < 3; round++ {
+		if _, err := tr.PairSwiss(); err != nil {
+			t.Fatal(err)
+		}
+		if round < 2 {
+			reportRound(t, tr)
+		}
+	}
+	if _, err := tr.StartTopCut(8); err == nil {
+		t.Error("top cut started before the round is done")
+	}
+	reportRound(t, tr)
+
+	// the best player drops, the ninth one gets the last seed
+	standings := tr.standings()
+	if err := tr.Drop(standings[0].Player); err != nil {
+		t.Fatal(err)
+	}
+	var seeds []int
+	for _, s := range standings[1:9] {
+		seeds = append(seeds, s.Player)
+	}
+
+	if _, err := tr.StartTopCut(6); err == nil {
+		t.Error("top cut of 6 players")
+	}
+	if _, err := tr.StartTopCut(16); err == nil {
+		t.Error("top cut of 16 with 10 players")
+	}
+	r, err := tr.StartTopCut(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.TopCut != 8 || !r.Elimination || len(r.Pairings) != 4 {
+		t.Fatalf("top cut %d, round %+v", tr.TopCut, r)
+	}
+	for i, pair := range bracketOrder(8) {
+		want := [2]int{seeds[pair[0]], seeds[pair[1]]}
+		if r.Pairings[i].Players != want {
+			t.Errorf("table %d: %v, want seeds %v: %v", i+1, r.Pairings[i].Players, pair, want)
+		}
+	}
+	if _, err := tr.PairSwiss(); err == nil {
+		t.Error("swiss round paired during the top cut")
+	}
+	if err := tr.ReportMatch(r.Number, 1, ocgcore.MatchResult{Wins: [2]int{1, 1}, Winner: 2}); err == nil {
+		t.Error("draw reported in the top cut")
+	}
+
+	for _, size := range []int{2, 1} {
+		reportRound(t, tr)
+		r, err = tr.NextElimination()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Pairings) != size {
+			t.Fatalf("%d pairings, want %d", len(r.Pairings), size)
+		}
+	}
+	if _, ok := tr.Winner(); ok {
+		t.Error("winner before the final is reported")
+	}
+	reportRound(t, tr)
+	if r, err := tr.NextElimination(); r != nil || err != nil {
+		t.Errorf("round %+v, %v after the final", r, err)
+	}
+	winner, ok := tr.Winner()
+	if !ok || winner.ID != seeds[0] {
+		t.Errorf("winner %+v, want the first seed %d", winner, seeds[0])
+	}
+}
@@ -5,37 +5,60 @@ import (
 	"encoding/binary"
 )
 
-func ReadUint8(b *bytes.Buffer) (v uint8) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+// ReadUint8 reads a little endian uint8 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Uint8, which reports the reads past the end.
+func ReadUint8(b *bytes.Buffer) uint8 {
+	return NewReader(b.Next(1)).Uint8()
 }
-func ReadUint16(b *bytes.Buffer) (v uint16) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadUint16 reads a little endian uint16 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Uint16, which reports the reads past the end.
+func ReadUint16(b *bytes.Buffer) uint16 {
+	return NewReader(b.Next(2)).Uint16()
 }
-func ReadUint32(b *bytes.Buffer) (v uint32) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadUint32 reads a little endian uint32 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Uint32, which reports the reads past the end.
+func ReadUint32(b *bytes.Buffer) uint32 {
+	return NewReader(b.Next(4)).Uint32()
 }
-func ReadUint64(b *bytes.Buffer) (v uint64) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadUint64 reads a little endian uint64 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Uint64, which reports the reads past the end.
+func ReadUint64(b *bytes.Buffer) uint64 {
+	return NewReader(b.Next(8)).Uint64()
 }
-func ReadInt8(b *bytes.Buffer) (v int8) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadInt8 reads a little endian int8 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Int8, which reports the reads past the end.
+func ReadInt8(b *bytes.Buffer) int8 {
+	return NewReader(b.Next(1)).Int8()
 }
-func ReadInt16(b *bytes.Buffer) (v int16) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadInt16 reads a little endian int16 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Int16, which reports the reads past the end.
+func ReadInt16(b *bytes.Buffer) int16 {
+	return NewReader(b.Next(2)).Int16()
 }
-func ReadInt32(b *bytes.Buffer) (v int32) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadInt32 reads a little endian int32 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Int32, which reports the reads past the end.
+func ReadInt32(b *bytes.Buffer) int32 {
+	return NewReader(b.Next(4)).Int32()
 }
-func ReadInt64(b *bytes.Buffer) (v int64) {
-	_ = binary.Read(b, binary.LittleEndian, &v)
-	return
+
+// ReadInt64 reads a little endian int64 from b, zero if b is too short.
+//
+// Deprecated: use Reader.Int64, which reports the reads past the end.
+func ReadInt64(b *bytes.Buffer) int64 {
+	return NewReader(b.Next(8)).Int64()
 }
 
 func WriteUint8(b *bytes.Buffer, v uint8) {
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ReadError is returned when reading past the end of a buffer. Field is the
+// name given to the read with Reader.Field, if any.
+type ReadError struct {
+	Field  string
+	Offset int
+	Size   int
+	Left   int
+}
+
+func (e *ReadError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("reading %s: %d bytes at offset %d, %d left", e.Field, e.Size, e.Offset, e.Left)
+	}
+	return fmt.Sprintf("reading %d bytes at offset %d, %d left", e.Size, e.Offset, e.Left)
+}
+
+// Reader reads little endian values from a buffer without copying it. A read
+// past the end returns zero and sets Err, the following reads return zero
+// too.
+type Reader struct {
+	buf   []byte
+	off   int
+	field string
+	err   error
+}
+
+func NewReader(b []byte) *Reader {
+	return &Reader{buf: b}
+}
+
+// Field names the next read, so that an error reports the field that was
+// expected.
+func (r *Reader) Field(name string) *Reader {
+	r.field = name
+	return r
+}
+
+// Err returns the first error.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Offset is the number of bytes read.
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// Len is the number of bytes left.
+func (r *Reader) Len() int {
+	return len(r.buf) - r.off
+}
+
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > r.Len() {
+		r.err = &ReadError{Field: r.field, Offset: r.off, Size: n, Left: r.Len()}
+		return nil
+	}
+	b := r.buf[r.off : r.off+n : r.off+n]
+	r.off += n
+	r.field = ""
+	return b
+}
+
+// Bytes returns the next n bytes, sharing the memory of the buffer.
+func (r *Reader) Bytes(n int) []byte {
+	return r.next(n)
+}
+
+func (r *Reader) Uint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *Reader) Uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *Reader) Uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *Reader) Uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// Count32 reads the number of items of a list. Each item takes at least a
+// byte, so a count larger than the bytes left is an error, that way a corrupt
+// length can't make the caller allocate gigabytes.
+func (r *Reader) Count32() int {
+	field, off := r.field, r.off
+	n := r.Uint32()
+	if r.err == nil && uint64(n) > uint64(r.Len()) {
+		r.err = &ReadError{Field: field, Offset: off, Size: int(n), Left: r.Len()}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *Reader) Int8() int8 {
+	return int8(r.Uint8())
+}
+
+func (r *Reader) Int16() int16 {
+	return int16(r.Uint16())
+}
+
+func (r *Reader) Int32() int32 {
+	return int32(r.Uint32())
+}
+
+func (r *Reader) Int64() int64 {
+	return int64(r.Uint64())
+}
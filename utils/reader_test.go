@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	r := NewReader([]byte{1, 2, 0, 3, 0, 0, 0, 'a', 'b'})
+	if v := r.Uint8(); v != 1 {
+		t.Fatalf("got %d", v)
+	}
+	if v := r.Uint16(); v != 2 {
+		t.Fatalf("got %d", v)
+	}
+	if v := r.Field("count").Uint32(); v != 3 {
+		t.Fatalf("got %d", v)
+	}
+	if b := r.Bytes(2); string(b) != "ab" {
+		t.Fatalf("got %q", b)
+	}
+	if r.Len() != 0 || r.Err() != nil {
+		t.Fatalf("%d left, error %v", r.Len(), r.Err())
+	}
+
+	if v := r.Field("code").Uint32(); v != 0 {
+		t.Fatalf("got %d past the end", v)
+	}
+	var e *ReadError
+	if !errors.As(r.Err(), &e) || e.Field != "code" || e.Offset != 9 || e.Size != 4 {
+		t.Fatalf("got %v", r.Err())
+	}
+	// the first error is kept
+	_ = r.Field("other").Uint8()
+	if r.Err() != e {
+		t.Fatalf("got %v", r.Err())
+	}
+}
+
+func TestReaderCount(t *testing.T) {
+	r := NewReader([]byte{2, 0, 0, 0, 1, 2})
+	if n := r.Count32(); n != 2 || r.Err() != nil {
+		t.Fatalf("got %d, %v", n, r.Err())
+	}
+
+	r = NewReader([]byte{0xff, 0xff, 0xff, 0xff, 1, 2})
+	if n := r.Field("cards").Count32(); n != 0 {
+		t.Fatalf("got %d", n)
+	}
+	var e *ReadError
+	if !errors.As(r.Err(), &e) || e.Field != "cards" {
+		t.Fatalf("got %v", r.Err())
+	}
+}
+
+func TestReadBuffer(t *testing.T) {
+	b := bytes.NewBuffer([]byte{1, 0xfe, 0xff, 3, 0, 0, 0, 4})
+	if v := ReadUint8(b); v != 1 {
+		t.Fatalf("got %d", v)
+	}
+	if v := ReadInt16(b); v != -2 {
+		t.Fatalf("got %d", v)
+	}
+	if v := ReadUint32(b); v != 3 {
+		t.Fatalf("got %d", v)
+	}
+	// a short read consumes the rest of the buffer
+	if v := ReadUint64(b); v != 0 || b.Len() != 0 {
+		t.Fatalf("got %d, %d left", v, b.Len())
+	}
+}
+
+// card is the layout of a card in most messages.
+var card = []byte{0xa3, 0xa2, 0x57, 0x05, 0, 0x04, 2, 0, 0, 0, 1, 0, 0, 0}
+
+// BenchmarkBinaryRead decodes cards the way the decoders did before Reader,
+// with binary.Read on a bytes.Buffer.
+func BenchmarkBinaryRead(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := bytes.NewBuffer(card)
+		var code, seq, pos uint32
+		var con, loc uint8
+		_ = binary.Read(buf, binary.LittleEndian, &code)
+		_ = binary.Read(buf, binary.LittleEndian, &con)
+		_ = binary.Read(buf, binary.LittleEndian, &loc)
+		_ = binary.Read(buf, binary.LittleEndian, &seq)
+		_ = binary.Read(buf, binary.LittleEndian, &pos)
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(card)
+		_ = r.Uint32()
+		_ = r.Uint8()
+		_ = r.Uint8()
+		_ = r.Uint32()
+		_ = r.Uint32()
+	}
+}